@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Built-in post-run actions.
+const (
+	ActionCopy    = "copy"
+	ActionEditor  = "editor"
+	ActionSaveAs  = "save_as"
+	ActionAsInput = "as_input"
+	ActionCommand = "command"
+)
+
+// postRunCommand is a user-defined post-run action, configured as
+// POST_RUN_COMMAND_<n>="<name>: <shell command>".
+type postRunCommand struct {
+	Name    string
+	Command string
+}
+
+var postRunCommandVar = regexp.MustCompile(`^POST_RUN_COMMAND_(\d+)=(.*)$`)
+
+// loadPostRunCommands reads the post-run commands from the environment,
+// ordered by their number.
+func loadPostRunCommands(environ []string) []postRunCommand {
+	type numbered struct {
+		n       int
+		command postRunCommand
+	}
+	var found []numbered
+	for _, entry := range environ {
+		match := postRunCommandVar.FindStringSubmatch(entry)
+		if match == nil {
+			continue
+		}
+		name, command, ok := strings.Cut(match[2], ":")
+		if !ok || strings.TrimSpace(command) == "" {
+			continue
+		}
+		n, _ := strconv.Atoi(match[1])
+		found = append(found, numbered{n: n, command: postRunCommand{Name: strings.TrimSpace(name), Command: strings.TrimSpace(command)}})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].n < found[j].n })
+
+	commands := make([]postRunCommand, len(found))
+	for i, f := range found {
+		commands[i] = f.command
+	}
+	return commands
+}
+
+// postRunItem is an entry of the post-run menu.
+type postRunItem struct {
+	action  string
+	title   string
+	desc    string
+	command string
+}
+
+func (i postRunItem) Title() string       { return i.title }
+func (i postRunItem) Description() string { return i.desc }
+func (i postRunItem) FilterValue() string { return i.title }
+
+// postRunDoneMsg reports the outcome of a post-run action that ran outside
+// of Update.
+type postRunDoneMsg struct {
+	status string
+	err    error
+}
+
+// postRunItems returns the actions offered for a finished run.
+func (m *model) postRunItems() []list.Item {
+	items := []list.Item{
+		postRunItem{action: ActionCopy, title: "Copy", desc: "Copy the result to the system clipboard"},
+		postRunItem{action: ActionEditor, title: "Open in editor", desc: "Open the result in " + editorCommand()[0]},
+		postRunItem{action: ActionSaveAs, title: "Save as", desc: "Save the result under a chosen name"},
+		postRunItem{action: ActionAsInput, title: "Use as input", desc: "Run another pattern on the result"},
+	}
+	for _, command := range m.config.PostRunCommands {
+		items = append(items, postRunItem{action: ActionCommand, title: command.Name, desc: command.Command, command: command.Command})
+	}
+	return items
+}
+
+// openPostRun shows the post-run menu for the focused run.
+func (m *model) openPostRun() {
+	m.state = "post_run"
+	m.actionStatus = ""
+	m.inputErr = nil
+	m.list.SetItems(m.postRunItems())
+	m.list.Select(0)
+}
+
+// runResult returns the result of a run: its saved output file, without the
+// front matter if the run added it, or the output that was shown.
+func runResult(r *run) []byte {
+	for _, sink := range r.invocation.Sinks {
+		if content, err := os.ReadFile(sink.Path); err == nil {
+			if r.invocation.FrontMatter {
+				content = stripFrontMatter(content)
+			}
+			return content
+		}
+	}
+	return []byte(r.output.String())
+}
+
+// resultFile returns a file holding the result of a run, writing a temporary
+// one if the output was not saved.
+func resultFile(r *run) (string, error) {
+	for _, sink := range r.invocation.Sinks {
+		if _, err := os.Stat(sink.Path); err == nil {
+			return sink.Path, nil
+		}
+	}
+	f, err := os.CreateTemp("", "fabricforge-*"+r.invocation.Pattern.outputExt())
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	_, err = f.WriteString(r.output.String())
+	return f.Name(), err
+}
+
+// updatePostRun handles key presses in the post-run menu.
+func (m model) updatePostRun(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		m.inputErr = nil
+		m.state = "executing"
+		return m, nil
+	case "enter":
+		item, ok := m.list.SelectedItem().(postRunItem)
+		if !ok {
+			return m, nil
+		}
+		m.actionStatus = ""
+		m.inputErr = nil
+		return m.runPostRunAction(item)
+	}
+
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+func (m model) runPostRunAction(item postRunItem) (tea.Model, tea.Cmd) {
+	switch item.action {
+	case ActionCopy:
+		how, err := copyToClipboard(runResult(m.run))
+		if err != nil {
+			m.inputErr = err
+		} else {
+			m.actionStatus = "Copied to the clipboard " + how
+		}
+		return m, nil
+	case ActionEditor:
+		path, err := resultFile(m.run)
+		if err != nil {
+			m.inputErr = err
+			return m, nil
+		}
+		editor := editorCommand()
+		cmd := exec.Command(editor[0], append(editor[1:], path)...)
+		return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
+			return postRunDoneMsg{status: "Closed " + path, err: err}
+		})
+	case ActionSaveAs:
+		m.state = "post_run_save"
+		m.textInput.Placeholder = "Path, relative to " + m.config.OutputDir
+		m.textInput.SetValue("")
+		return m, m.textInput.Focus()
+	case ActionAsInput:
+		path, err := resultFile(m.run)
+		if err != nil {
+			m.inputErr = err
+			return m, nil
+		}
+		m.inputSource = FileSource{Path: path, SkipFrontMatter: m.run.invocation.FrontMatter}
+		m.state = "selecting"
+		m.list.SetItems(m.filteredItems)
+		return m, m.suggestPatterns()
+	case ActionCommand:
+		path, err := resultFile(m.run)
+		if err != nil {
+			m.inputErr = err
+			return m, nil
+		}
+		m.actionStatus = "Running " + item.title + "…"
+		return m, runPostRunCommand(item.title, item.command, path, m.run)
+	}
+	return m, nil
+}
+
+// updatePostRunSave handles key presses while the file name for "Save as"
+// is entered.
+func (m model) updatePostRunSave(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.inputErr = nil
+		m.state = "post_run"
+		m.textInput.Placeholder = m.config.Placeholder
+		return m, nil
+	case "enter":
+		path := strings.TrimSpace(m.textInput.Value())
+		if path == "" {
+			return m, nil
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(m.config.OutputDir, path)
+		}
+		if filepath.Ext(path) == "" {
+			path += m.run.invocation.Pattern.outputExt()
+		}
+		if err := saveResult(path, runResult(m.run)); err != nil {
+			m.inputErr = err
+			return m, nil
+		}
+		m.inputErr = nil
+		m.actionStatus = "Saved to " + path
+		m.state = "post_run"
+		m.textInput.Placeholder = m.config.Placeholder
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.textInput, cmd = m.textInput.Update(msg)
+	return m, cmd
+}
+
+// saveResult writes the result to a new file at path.
+func saveResult(path string, result []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return fmt.Errorf("%s already exists", path)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(result); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// runPostRunCommand runs a user-defined command through the shell with the
+// result on its stdin. The result file and the pattern are also passed in
+// the FABRICFORGE_OUTPUT_FILE and FABRICFORGE_PATTERN environment variables.
+func runPostRunCommand(name, command, path string, r *run) tea.Cmd {
+	result := runResult(r)
+	pattern := r.invocation.Pattern.DirName
+	return func() tea.Msg {
+		shell := []string{"sh", "-c"}
+		if runtime.GOOS == "windows" {
+			shell = []string{"cmd", "/C"}
+		}
+		cmd := exec.Command(shell[0], append(shell[1:], command)...)
+		cmd.Stdin = bytes.NewReader(result)
+		cmd.Env = append(os.Environ(), "FABRICFORGE_OUTPUT_FILE="+path, "FABRICFORGE_PATTERN="+pattern)
+		output, err := cmd.CombinedOutput()
+
+		status := name + " finished"
+		if text := strings.TrimSpace(string(output)); text != "" {
+			lines := strings.Split(text, "\n")
+			status += ": " + lines[len(lines)-1]
+		}
+		if err != nil {
+			err = fmt.Errorf("%s: %w", name, err)
+		}
+		return postRunDoneMsg{status: status, err: err}
+	}
+}
+
+// editorCommand returns the user's editor from $VISUAL or $EDITOR, split into
+// words.
+func editorCommand() []string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.Fields(os.Getenv(name)); len(editor) > 0 {
+			return editor
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+// copyToClipboard copies text with a clipboard tool, or with the OSC 52
+// terminal escape sequence over SSH or when no tool is installed. It returns
+// how the text was copied.
+func copyToClipboard(text []byte) (string, error) {
+	if tool := detectClipboardWriter(); tool != nil && !overSSH() {
+		cmd := exec.Command(tool[0], tool[1:]...)
+		cmd.Stdin = bytes.NewReader(text)
+		if err := cmd.Run(); err == nil {
+			return "with " + tool[0], nil
+		}
+	}
+
+	var out io.Writer = os.Stderr
+	if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+		defer tty.Close()
+		out = tty
+	}
+	seq := osc52.New(string(text))
+	if os.Getenv("TMUX") != "" {
+		seq = seq.Tmux()
+	} else if strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		seq = seq.Screen()
+	}
+	if _, err := seq.WriteTo(out); err != nil {
+		return "", fmt.Errorf("copying with OSC 52: %w", err)
+	}
+	return "through the terminal (OSC 52)", nil
+}
+
+// detectClipboardWriter returns the command used to write the clipboard on
+// this system, or nil if none of the supported tools is installed.
+func detectClipboardWriter() []string {
+	var candidates [][]string
+	if runtime.GOOS == "darwin" {
+		candidates = append(candidates, []string{"pbcopy"})
+	}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		candidates = append(candidates, []string{"wl-copy"})
+	}
+	candidates = append(candidates,
+		[]string{"xclip", "-selection", "clipboard", "-i"},
+		[]string{"xsel", "--clipboard", "--input"},
+		[]string{"pbcopy"},
+	)
+	for _, candidate := range candidates {
+		if _, err := exec.LookPath(candidate[0]); err == nil {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// overSSH reports whether FabricForge runs in an SSH session, where the
+// local clipboard tools would not reach the user's clipboard.
+func overSSH() bool {
+	return os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != ""
+}
+
+func (m model) postRunView() string {
+	var content string
+	if m.state == "post_run_save" {
+		content = lipgloss.JoinVertical(lipgloss.Left,
+			"Save the result as (enter to save, esc to cancel):",
+			m.textInput.View(),
+		)
+	} else {
+		content = lipgloss.JoinVertical(lipgloss.Left,
+			fmt.Sprintf("Result of %s (enter to run an action, esc to go back):", m.run.invocation.Pattern.DirName),
+			m.list.View(),
+		)
+	}
+	if m.actionStatus != "" {
+		content = lipgloss.JoinVertical(lipgloss.Left, statusStyle.Render(m.actionStatus), content)
+	}
+	if m.inputErr != nil {
+		content = lipgloss.JoinVertical(lipgloss.Left, errorStyle.Render(m.inputErr.Error()), content)
+	}
+	return content
+}
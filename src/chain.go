@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// patternMarks holds the patterns marked in the list, in the order they will
+// run.
+type patternMarks struct {
+	order []Pattern
+}
+
+// toggle marks the pattern, or unmarks it if it is already marked.
+func (pm *patternMarks) toggle(pattern Pattern) {
+	if i := pm.position(pattern.DirName); i > 0 {
+		pm.remove(i - 1)
+		return
+	}
+	pm.order = append(pm.order, pattern)
+}
+
+// position returns the 1-based position of the pattern, or 0 if it is not
+// marked.
+func (pm *patternMarks) position(dirName string) int {
+	for i, p := range pm.order {
+		if p.DirName == dirName {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// move shifts the pattern at index i by delta places and returns its new
+// index.
+func (pm *patternMarks) move(i, delta int) int {
+	j := i + delta
+	if i < 0 || i >= len(pm.order) || j < 0 || j >= len(pm.order) {
+		return i
+	}
+	pm.order[i], pm.order[j] = pm.order[j], pm.order[i]
+	return j
+}
+
+func (pm *patternMarks) remove(i int) {
+	if i < 0 || i >= len(pm.order) {
+		return
+	}
+	pm.order = append(pm.order[:i], pm.order[i+1:]...)
+}
+
+func (pm *patternMarks) clear() {
+	pm.order = nil
+}
+
+func (pm *patternMarks) items() []list.Item {
+	items := make([]list.Item, len(pm.order))
+	for i, p := range pm.order {
+		items[i] = p
+	}
+	return items
+}
+
+// describe renders the marked patterns as "a → b → c".
+func (pm *patternMarks) describe() string {
+	names := make([]string, len(pm.order))
+	for i, p := range pm.order {
+		names[i] = p.DirName
+	}
+	return strings.Join(names, " → ")
+}
+
+// runIDLayout formats the start time of a chain or fan-out into the name of
+// the directory shared by its outputs.
+const runIDLayout = "20060102-150405.000"
+
+// newRunID returns an identifier shared by all outputs of one execution.
+func newRunID() string {
+	return time.Now().Format(runIDLayout)
+}
+
+// chainInput points a chain step that reads the output of an earlier step at
+// the path that output was actually saved to, once {hash} and {slug} have
+// been expanded and the name made unique.
+func chainInput(step Invocation, steps []Invocation, runs []*run) Invocation {
+	source, ok := step.Input.(FileSource)
+	if !ok {
+		return step
+	}
+	for i, r := range runs {
+		for j, sink := range steps[i].Sinks {
+			if sink.Path == source.Path && j < len(r.invocation.Sinks) {
+				source.Path = r.invocation.Sinks[j].Path
+				step.Input = source
+				return step
+			}
+		}
+	}
+	return step
+}
+
+// buildChain returns one invocation per pattern, where each step reads the
+// output the previous step saved under OutputDir/chain_<run id>. Outputs are
+// named by OUTPUT_FILE_TEMPLATE, prefixed with the step number.
+func (m *model) buildChain(patterns []Pattern) []Invocation {
+	now := time.Now()
+	dir := filepath.Join(m.config.OutputDir, "chain_"+now.Format(runIDLayout))
+
+	steps := make([]Invocation, len(patterns))
+	var previous string
+	for i, pattern := range patterns {
+		step := m.buildFabricCommand(pattern)
+		output := outputFilePath(m.config.OutputTemplate, dir, pattern, step.Model, now)
+		output = filepath.Join(filepath.Dir(output), fmt.Sprintf("%02d_%s", i+1, filepath.Base(output)))
+		step.Sinks = []OutputSink{{Kind: SinkTee, Path: output}}
+		if previous != "" {
+			step.Input = FileSource{Path: previous, SkipFrontMatter: step.FrontMatter}
+		}
+		steps[i] = step
+		previous = output
+	}
+	return steps
+}
+
+// buildFanout returns one invocation per pattern, all reading the current
+// input and each saving its output under OutputDir/fanout_<run id>, named by
+// OUTPUT_FILE_TEMPLATE.
+func (m *model) buildFanout(patterns []Pattern) []Invocation {
+	now := time.Now()
+	dir := filepath.Join(m.config.OutputDir, "fanout_"+now.Format(runIDLayout))
+
+	steps := make([]Invocation, len(patterns))
+	for i, pattern := range patterns {
+		step := m.buildFabricCommand(pattern)
+		output := outputFilePath(m.config.OutputTemplate, dir, pattern, step.Model, now)
+		step.Sinks = []OutputSink{{Kind: SinkTee, Path: output}}
+		steps[i] = step
+	}
+	return steps
+}
+
+// variableGroup holds the template variables of the marked patterns while
+// their values are asked for, before a chain or fan-out runs.
+type variableGroup struct {
+	fields   []groupVariable
+	fanout   bool
+	returnTo string
+}
+
+// groupVariable is a template variable of one of the marked patterns.
+type groupVariable struct {
+	dirName string
+	name    string
+}
+
+func (v groupVariable) label() string { return v.dirName + "." + v.name }
+
+// runMarked runs the marked patterns as a chain, or concurrently for a
+// fan-out. If any of them has template variables, their values are asked
+// for first.
+func (m model) runMarked(fanout bool) (tea.Model, tea.Cmd) {
+	m.rerun = nil
+	var fields []groupVariable
+	for _, pattern := range m.marks.order {
+		for _, name := range patternVariables(m.config.PatternsDir, pattern) {
+			fields = append(fields, groupVariable{dirName: pattern.DirName, name: name})
+		}
+	}
+	if len(fields) == 0 {
+		return m.startMarked(fanout)
+	}
+
+	labels := make([]string, len(fields))
+	values := map[string]string{}
+	for i, field := range fields {
+		labels[i] = field.label()
+		values[labels[i]] = m.options.get(field.dirName).Variables[field.name]
+	}
+	m.group = variableGroup{fields: fields, fanout: fanout, returnTo: m.state}
+	m.variablesForm = newVariablesForm(labels, values)
+	m.inputErr = nil
+	m.state = "editing_group_variables"
+	return m, textinput.Blink
+}
+
+func (m model) startMarked(fanout bool) (tea.Model, tea.Cmd) {
+	if fanout {
+		return m.executeFanout(m.buildFanout(m.marks.order))
+	}
+	return m.execute(m.buildChain(m.marks.order))
+}
+
+// updateGroupVariables handles key presses in the template variables form
+// of a chain or fan-out. The values are saved per pattern, like those of a
+// single run.
+func (m model) updateGroupVariables(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.state = m.group.returnTo
+		return m, nil
+	case "enter":
+		values := m.variablesForm.values()
+		for _, field := range m.group.fields {
+			options := m.options.get(field.dirName)
+			variables := make(map[string]string, len(options.Variables)+1)
+			for name, value := range options.Variables {
+				variables[name] = value
+			}
+			variables[field.name] = values[field.label()]
+			options.Variables = variables
+			if err := m.options.set(field.dirName, options); err != nil {
+				m.inputErr = fmt.Errorf("saving variables: %w", err)
+				return m, nil
+			}
+		}
+		return m.startMarked(m.group.fanout)
+	}
+
+	var cmd tea.Cmd
+	m.variablesForm, cmd = m.variablesForm.update(msg)
+	return m, cmd
+}
+
+func (m model) groupVariablesView() string {
+	kind := "chain"
+	if m.group.fanout {
+		kind = "fan-out"
+	}
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		fmt.Sprintf("Template variables for the %s (tab/↑/↓ to move, enter to save and run, esc to cancel):", kind),
+		commandStyle.Render(m.marks.describe()),
+		m.variablesForm.view(),
+	)
+	if m.inputErr != nil {
+		content = lipgloss.JoinVertical(lipgloss.Left, errorStyle.Render(m.inputErr.Error()), content)
+	}
+	return content
+}
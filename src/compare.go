@@ -0,0 +1,311 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	removedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5F5F"))
+	addedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#5FFF87"))
+	removedWord  = lipgloss.NewStyle().Foreground(lipgloss.Color("#FAFAFA")).Background(lipgloss.Color("#AF0000"))
+	addedWord    = lipgloss.NewStyle().Foreground(lipgloss.Color("#1C1C1C")).Background(lipgloss.Color("#5FD75F"))
+)
+
+// diffRow is one row of the side-by-side diff. A nil side is empty.
+type diffRow struct {
+	left, right *string
+}
+
+func (r diffRow) changed() bool {
+	return r.left == nil || r.right == nil || *r.left != *r.right
+}
+
+// comparison is the state of the diff view between two saved outputs.
+type comparison struct {
+	oldPath, newPath string
+	ops              []diffOp
+	rows             []diffRow
+	// words highlights the changed words of modified lines instead of
+	// whole lines.
+	words    bool
+	changes  []int
+	change   int
+	viewport viewport.Model
+	status   string
+	err      error
+	back     string
+}
+
+// markForDiff remembers path as the first side of a comparison, or opens
+// the comparison with the output marked before.
+func (m *model) markForDiff(path string) {
+	switch m.diffBase {
+	case "":
+		m.diffBase = path
+	case path:
+		m.diffBase = ""
+	default:
+		m.inputErr = m.openComparison(m.diffBase, path)
+		if m.inputErr == nil {
+			m.diffBase = ""
+		}
+	}
+}
+
+// diffHint describes the output marked for comparison.
+func (m *model) diffHint() string {
+	if m.diffBase == "" {
+		return ""
+	}
+	return fmt.Sprintf("Comparing with %s: press d on another output", m.diffBase)
+}
+
+// openComparison shows the diff between two saved outputs. Front matter is
+// left out since it always differs.
+func (m *model) openComparison(oldPath, newPath string) error {
+	oldContent, err := os.ReadFile(oldPath)
+	if err != nil {
+		return err
+	}
+	newContent, err := os.ReadFile(newPath)
+	if err != nil {
+		return err
+	}
+
+	if m.config.FrontMatter {
+		oldContent, newContent = stripFrontMatter(oldContent), stripFrontMatter(newContent)
+	}
+	ops, err := diffTokens(splitLines(string(oldContent)), splitLines(string(newContent)))
+	if err != nil {
+		return err
+	}
+	m.comparison = comparison{
+		oldPath:  oldPath,
+		newPath:  newPath,
+		ops:      ops,
+		rows:     diffRows(ops),
+		words:    true,
+		change:   -1,
+		viewport: viewport.New(m.viewport.Width, max(m.height-5, 1)),
+		back:     m.state,
+	}
+	m.comparison.render()
+	m.state = "comparing"
+	return nil
+}
+
+// resizeComparison fits the diff view to the window.
+func (m *model) resizeComparison() {
+	m.comparison.viewport.Width = m.viewport.Width
+	m.comparison.viewport.Height = max(m.height-5, 1)
+	if m.state == "comparing" {
+		m.comparison.render()
+	}
+}
+
+// diffRows pairs the removed and added lines of each change side by side.
+func diffRows(ops []diffOp) []diffRow {
+	var rows []diffRow
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			rows = append(rows, diffRow{left: &ops[i].text, right: &ops[i].text})
+			i++
+			continue
+		}
+		var removed, added []*string
+		for ; i < len(ops) && ops[i].kind != ' '; i++ {
+			if ops[i].kind == '-' {
+				removed = append(removed, &ops[i].text)
+			} else {
+				added = append(added, &ops[i].text)
+			}
+		}
+		for j := 0; j < max(len(removed), len(added)); j++ {
+			var row diffRow
+			if j < len(removed) {
+				row.left = removed[j]
+			}
+			if j < len(added) {
+				row.right = added[j]
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// render lays out the rows in two columns and records where each change
+// starts.
+func (c *comparison) render() {
+	width := max((c.viewport.Width-3)/2, 10)
+	column := lipgloss.NewStyle().Width(width)
+
+	var lines []string
+	c.changes = nil
+	for i, row := range c.rows {
+		if row.changed() && (i == 0 || !c.rows[i-1].changed()) {
+			c.changes = append(c.changes, len(lines))
+		}
+		left, right := c.renderSides(row)
+		leftLines := strings.Split(column.Render(left), "\n")
+		rightLines := strings.Split(column.Render(right), "\n")
+		for j := 0; j < max(len(leftLines), len(rightLines)); j++ {
+			l, r := strings.Repeat(" ", width), ""
+			if j < len(leftLines) {
+				l = leftLines[j]
+			}
+			if j < len(rightLines) {
+				r = rightLines[j]
+			}
+			lines = append(lines, l+" │ "+r)
+		}
+	}
+	c.change = min(c.change, len(c.changes)-1)
+	c.viewport.SetContent(strings.Join(lines, "\n"))
+}
+
+// renderSides styles both sides of a row.
+func (c *comparison) renderSides(row diffRow) (string, string) {
+	switch {
+	case !row.changed():
+		return *row.left, *row.right
+	case row.left == nil:
+		return "", addedStyle.Render(*row.right)
+	case row.right == nil:
+		return removedStyle.Render(*row.left), ""
+	case !c.words:
+		return removedStyle.Render(*row.left), addedStyle.Render(*row.right)
+	}
+
+	ops, err := diffTokens(splitWords(*row.left), splitWords(*row.right))
+	if err != nil {
+		return removedStyle.Render(*row.left), addedStyle.Render(*row.right)
+	}
+	var left, right strings.Builder
+	for _, op := range ops {
+		switch op.kind {
+		case ' ':
+			left.WriteString(op.text)
+			right.WriteString(op.text)
+		case '-':
+			left.WriteString(removedWord.Render(op.text))
+		case '+':
+			right.WriteString(addedWord.Render(op.text))
+		}
+	}
+	return left.String(), right.String()
+}
+
+// stats counts the removed and added lines.
+func (c *comparison) stats() (removed, added int) {
+	for _, op := range c.ops {
+		switch op.kind {
+		case '-':
+			removed++
+		case '+':
+			added++
+		}
+	}
+	return removed, added
+}
+
+// exportPatch writes the diff as a unified patch to the output directory.
+func (c *comparison) exportPatch(outputDir string) (string, error) {
+	path := filepath.Join(outputDir, "diff_"+newRunID()+".patch")
+	patch := unifiedDiff(patchName(outputDir, c.oldPath), patchName(outputDir, c.newPath), c.ops, 3)
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, []byte(patch), 0644)
+}
+
+// patchName names a file in a patch header, relative to the output
+// directory when it is inside it.
+func patchName(outputDir, path string) string {
+	if rel, err := filepath.Rel(outputDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(path)
+}
+
+// truncateLeft shortens s to width cells by dropping its beginning.
+func truncateLeft(s string, width int) string {
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && lipgloss.Width(string(runes))+1 > width {
+		runes = runes[1:]
+	}
+	return "…" + string(runes)
+}
+
+// updateComparison handles key presses in the diff view.
+func (m model) updateComparison(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	c := &m.comparison
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		m.state = c.back
+		return m, nil
+	case "w":
+		c.words = !c.words
+		c.render()
+		return m, nil
+	case "n", "N":
+		if n := len(c.changes); n > 0 {
+			if msg.String() == "n" {
+				c.change = (c.change + 1) % n
+			} else if c.change <= 0 {
+				c.change = n - 1
+			} else {
+				c.change--
+			}
+			c.viewport.SetYOffset(c.changes[c.change])
+		}
+		return m, nil
+	case "p":
+		path, err := c.exportPatch(m.config.OutputDir)
+		c.err = err
+		if err == nil {
+			c.status = "Patch saved to " + path
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	c.viewport, cmd = c.viewport.Update(msg)
+	return m, cmd
+}
+
+func (m model) comparisonView() string {
+	c := m.comparison
+	width := max((c.viewport.Width-3)/2, 10)
+	side := lipgloss.NewStyle().Width(width)
+	header := side.Render(removedStyle.Render("− "+truncateLeft(c.oldPath, width-2))) + " │ " + addedStyle.Render("+ "+truncateLeft(c.newPath, width-2))
+
+	removed, added := c.stats()
+	mode := "line"
+	if c.words {
+		mode = "word"
+	}
+	status := fmt.Sprintf("%d changes, −%d +%d lines, %s diff", len(c.changes), removed, added, mode)
+	if c.status != "" {
+		status += "  " + c.status
+	}
+
+	sections := []string{header, c.viewport.View(), statusStyle.Render(status)}
+	if c.err != nil {
+		sections = append(sections, errorStyle.Render(c.err.Error()))
+	}
+	sections = append(sections, "↑/↓ to scroll, n/N for next/previous change, w for word/line diff, p to export a patch, esc to go back")
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
+}
@@ -2,20 +2,34 @@ package main
 
 import (
 	"os"
+	"path/filepath"
 	"strconv"
+	"time"
 )
 
 type Config struct {
-	Width         int
-	Height        int
-	Title         string
-	Placeholder   string
-	MetadataPath  string
-	AlphaSort     bool
-	SortByDirName bool
-	OutputDir     string
-	StreamResults bool
-	OutputResults bool
+	Width           int
+	Height          int
+	Title           string
+	Placeholder     string
+	MetadataPath    string
+	AlphaSort       bool
+	SortByDirName   bool
+	OutputDir       string
+	StreamResults   bool
+	OutputResults   bool
+	InputSource     string
+	InputFile       string
+	Concurrency     int
+	RunTimeout      time.Duration
+	Backend         string
+	PatternsDir     string
+	LLMBaseURL      string
+	LLMAPIKey       string
+	LLMModel        string
+	OutputTemplate  string
+	FrontMatter     bool
+	PostRunCommands []postRunCommand
 }
 
 func loadConfig() Config {
@@ -25,17 +39,45 @@ func loadConfig() Config {
 	sortByDirName, _ := strconv.ParseBool(os.Getenv("SORT_BY_DIR_NAME"))
 	streamResults, _ := strconv.ParseBool(os.Getenv("STREAM_RESULTS"))
 	outputResults, _ := strconv.ParseBool(os.Getenv("OUTPUT_RESULTS"))
+	frontMatter, _ := strconv.ParseBool(os.Getenv("OUTPUT_FRONT_MATTER"))
+	concurrency, err := strconv.Atoi(os.Getenv("FANOUT_CONCURRENCY"))
+	if err != nil || concurrency < 1 {
+		concurrency = 3
+	}
+	runTimeout, _ := time.ParseDuration(os.Getenv("RUN_TIMEOUT"))
 
 	return Config{
-		Width:         width,
-		Height:        height,
-		Title:         os.Getenv("CLI_TITLE"),
-		Placeholder:   os.Getenv("CLI_PLACEHOLDER"),
-		MetadataPath:  os.Getenv("MERGED_PATTERNS_METADATA_PATH"),
-		AlphaSort:     alphaSort,
-		SortByDirName: sortByDirName,
-		OutputDir:     os.Getenv("OUTPUT_DIR"),
-		StreamResults: streamResults,
-		OutputResults: outputResults,
+		Width:           width,
+		Height:          height,
+		Title:           os.Getenv("CLI_TITLE"),
+		Placeholder:     os.Getenv("CLI_PLACEHOLDER"),
+		MetadataPath:    os.Getenv("MERGED_PATTERNS_METADATA_PATH"),
+		AlphaSort:       alphaSort,
+		SortByDirName:   sortByDirName,
+		OutputDir:       os.Getenv("OUTPUT_DIR"),
+		StreamResults:   streamResults,
+		OutputResults:   outputResults,
+		InputSource:     os.Getenv("INPUT_SOURCE"),
+		InputFile:       os.Getenv("INPUT_FILE"),
+		Concurrency:     concurrency,
+		RunTimeout:      runTimeout,
+		Backend:         os.Getenv("BACKEND"),
+		PatternsDir:     os.Getenv("FABRIC_PATTERNS_DIRECTORY_PATH"),
+		LLMBaseURL:      os.Getenv("LLM_BASE_URL"),
+		LLMAPIKey:       os.Getenv("LLM_API_KEY"),
+		LLMModel:        os.Getenv("LLM_MODEL"),
+		OutputTemplate:  os.Getenv("OUTPUT_FILE_TEMPLATE"),
+		FrontMatter:     frontMatter,
+		PostRunCommands: loadPostRunCommands(os.Environ()),
+	}
+}
+
+// resolvePaths makes the relative paths from the .env file relative to the
+// directory the file was loaded from.
+func (c *Config) resolvePaths(envDir string) {
+	for _, path := range []*string{&c.MetadataPath, &c.OutputDir, &c.PatternsDir} {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(envDir, *path)
+		}
 	}
 }
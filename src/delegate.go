@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// patternDelegate renders list items like the default delegate, but prefixes
+// patterns marked for a chain with their position in it and patterns
+// suggested for the input with a star, and highlights the characters matched
+// by the search.
+type patternDelegate struct {
+	list.DefaultDelegate
+	marks       *patternMarks
+	highlights  *patternHighlights
+	suggestions *patternSuggestions
+}
+
+func newPatternDelegate(marks *patternMarks, highlights *patternHighlights, suggestions *patternSuggestions) patternDelegate {
+	return patternDelegate{DefaultDelegate: list.NewDefaultDelegate(), marks: marks, highlights: highlights, suggestions: suggestions}
+}
+
+func (d patternDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	pattern, ok := item.(Pattern)
+	if !ok {
+		d.DefaultDelegate.Render(w, m, index, item)
+		return
+	}
+
+	var titled list.DefaultItem = pattern
+	prefix := 0
+	pos, suggested := d.marks.position(pattern.DirName), d.suggestions.has(pattern.DirName)
+	if pos > 0 || suggested {
+		marked := markedPattern{Pattern: pattern, position: pos, suggested: suggested}
+		prefix = utf8.RuneCountInString(marked.Title()) - utf8.RuneCountInString(pattern.Title())
+		titled = marked
+	}
+
+	match, ok := d.highlights.get(pattern.DirName)
+	if !ok || m.Width() <= 0 {
+		d.DefaultDelegate.Render(w, m, index, titled)
+		return
+	}
+	titleRunes := make([]int, len(match.title))
+	for i, pos := range match.title {
+		titleRunes[i] = pos + prefix
+	}
+	d.renderHighlighted(w, m, index, titled, titleRunes, match.desc)
+}
+
+// renderHighlighted renders an item the way the default delegate renders
+// the matches of the list's own filter.
+func (d patternDelegate) renderHighlighted(w io.Writer, m list.Model, index int, item list.DefaultItem, titleRunes, descRunes []int) {
+	s := &d.Styles
+	textwidth := m.Width() - s.NormalTitle.GetPaddingLeft() - s.NormalTitle.GetPaddingRight()
+	title := ansi.Truncate(item.Title(), textwidth, "…")
+	desc := ansi.Truncate(strings.SplitN(item.Description(), "\n", 2)[0], textwidth, "…")
+
+	titleStyle, descStyle := s.NormalTitle, s.NormalDesc
+	if index == m.Index() {
+		titleStyle, descStyle = s.SelectedTitle, s.SelectedDesc
+	}
+	highlight := func(text string, runes []int, style lipgloss.Style) string {
+		unmatched := style.Inline(true)
+		return lipgloss.StyleRunes(text, runes, unmatched.Inherit(s.FilterMatch), unmatched)
+	}
+	title = titleStyle.Render(highlight(title, titleRunes, titleStyle))
+	desc = descStyle.Render(highlight(desc, descRunes, descStyle))
+
+	if d.ShowDescription {
+		fmt.Fprintf(w, "%s\n%s", title, desc)
+		return
+	}
+	fmt.Fprint(w, title)
+}
+
+type markedPattern struct {
+	Pattern
+	position  int
+	suggested bool
+}
+
+func (p markedPattern) Title() string {
+	title := p.Pattern.Title()
+	if p.suggested {
+		title = "★ " + title
+	}
+	if p.position > 0 {
+		title = fmt.Sprintf("[%d] %s", p.position, title)
+	}
+	return title
+}
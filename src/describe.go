@@ -0,0 +1,187 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+// BM25 parameters: k1 limits how much repeating a term helps, b how much
+// long documents are penalised.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Field weights for the relevance index: a term counts this many times
+// when it appears in the field.
+const (
+	relevanceNameWeight = 3
+	relevanceTagWeight  = 2
+	relevanceDescWeight = 1
+)
+
+// stopWords are left out of the relevance index and of queries.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "can": true, "for": true, "from": true, "how": true,
+	"i": true, "in": true, "into": true, "is": true, "it": true, "its": true,
+	"me": true, "my": true, "of": true, "on": true, "or": true, "out": true,
+	"some": true, "that": true, "the": true, "them": true, "this": true,
+	"to": true, "want": true, "what": true, "which": true,
+	"with": true, "you": true, "your": true, "get": true, "make": true,
+	"give": true, "help": true, "need": true, "pattern": true, "patterns": true,
+}
+
+// stemSuffixes are stripped from words, first match only, so that
+// "summarize", "summarizes" and "summarization" index the same.
+var stemSuffixes = []struct{ suffix, replacement string }{
+	{"ations", ""}, {"ation", ""}, {"ings", ""}, {"ing", ""}, {"ies", "y"},
+	{"es", ""}, {"ed", ""}, {"s", ""}, {"e", ""},
+}
+
+// stem reduces a lower-case word to a crude stem.
+func stem(word string) string {
+	for _, s := range stemSuffixes {
+		if strings.HasSuffix(word, s.suffix) && len(word)-len(s.suffix) >= 3 {
+			return strings.TrimSuffix(word, s.suffix) + s.replacement
+		}
+	}
+	return word
+}
+
+// relevanceTerms splits text into stemmed terms, without stop words.
+func relevanceTerms(text string) []string {
+	var terms []string
+	for _, word := range searchTerms(text) {
+		if !stopWords[word] {
+			terms = append(terms, stem(word))
+		}
+	}
+	return terms
+}
+
+// relevanceDoc is a pattern in the relevance index.
+type relevanceDoc struct {
+	item   list.Item
+	terms  map[string]int
+	length int
+}
+
+// relevanceIndex ranks patterns against a plain-language description of
+// what the user wants, with BM25 over their names, descriptions, tags and
+// categories. It is built once, when the patterns are loaded.
+type relevanceIndex struct {
+	docs      []relevanceDoc
+	docFreq   map[string]int
+	avgLength float64
+}
+
+func newRelevanceIndex(patterns []list.Item) *relevanceIndex {
+	x := &relevanceIndex{docFreq: map[string]int{}}
+	total := 0
+	for _, item := range patterns {
+		pattern := item.(Pattern)
+		doc := relevanceDoc{item: item, terms: map[string]int{}}
+		add := func(text string, weight int) {
+			for _, term := range relevanceTerms(text) {
+				doc.terms[term] += weight
+				doc.length += weight
+			}
+		}
+		add(pattern.FriendlyName, relevanceNameWeight)
+		add(strings.ReplaceAll(pattern.DirName, "_", " "), relevanceNameWeight)
+		add(strings.Join(pattern.Tags, " "), relevanceTagWeight)
+		add(strings.Join(pattern.Categories, " "), relevanceTagWeight)
+		add(pattern.ShortDesc, relevanceDescWeight)
+		add(pattern.LongDesc, relevanceDescWeight)
+
+		for term := range doc.terms {
+			x.docFreq[term]++
+		}
+		total += doc.length
+		x.docs = append(x.docs, doc)
+	}
+	if len(x.docs) > 0 {
+		x.avgLength = float64(total) / float64(len(x.docs))
+	}
+	return x
+}
+
+// search returns the patterns that share a term with the query, most
+// relevant first, along with the words to highlight.
+func (x *relevanceIndex) search(query string) ([]list.Item, map[string]patternMatch) {
+	terms := relevanceTerms(query)
+	if len(terms) == 0 || x.avgLength == 0 {
+		return nil, nil
+	}
+	wanted := map[string]bool{}
+	for _, term := range terms {
+		wanted[term] = true
+	}
+
+	type scored struct {
+		item  list.Item
+		score float64
+	}
+	var results []scored
+	n := float64(len(x.docs))
+	for _, doc := range x.docs {
+		score := 0.0
+		for term := range wanted {
+			tf := float64(doc.terms[term])
+			if tf == 0 {
+				continue
+			}
+			df := float64(x.docFreq[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(doc.length)/x.avgLength)
+			score += idf * tf * (bm25K1 + 1) / (tf + norm)
+		}
+		if score > 0 {
+			results = append(results, scored{item: doc.item, score: score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+
+	items := make([]list.Item, len(results))
+	matches := make(map[string]patternMatch, len(results))
+	for i, r := range results {
+		items[i] = r.item
+		pattern := r.item.(Pattern)
+		matches[pattern.DirName] = patternMatch{
+			title: matchedWords(pattern.FriendlyName, wanted),
+			desc:  matchedWords(pattern.ShortDesc, wanted),
+		}
+	}
+	return items, matches
+}
+
+// matchedWords returns the rune positions of the words in text whose stem
+// is one of the terms.
+func matchedWords(text string, terms map[string]bool) []int {
+	var positions []int
+	runes := []rune(text)
+	for start := 0; start < len(runes); {
+		if !unicode.IsLetter(runes[start]) && !unicode.IsDigit(runes[start]) {
+			start++
+			continue
+		}
+		end := start
+		for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end])) {
+			end++
+		}
+		if terms[stem(strings.ToLower(string(runes[start:end])))] {
+			for i := start; i < end; i++ {
+				positions = append(positions, i)
+			}
+		}
+		start = end
+	}
+	return positions
+}
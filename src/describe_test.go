@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+func TestRelevanceSearch(t *testing.T) {
+	patterns := []list.Item{
+		Pattern{
+			DirName: "summarize", FriendlyName: "Summarize",
+			ShortDesc: "Summarizes content into a short summary", Tags: []string{"summarization"},
+		},
+		Pattern{
+			DirName: "summarize_git_diff", FriendlyName: "Summarize Git Diff",
+			ShortDesc: "Summarizes the changes in a git diff", Tags: []string{"git"},
+		},
+		Pattern{
+			DirName: "explain_code", FriendlyName: "Explain Code",
+			ShortDesc: "Explains what a piece of code does", Tags: []string{"code examples"},
+		},
+		Pattern{
+			DirName: "analyze_email_headers", FriendlyName: "Analyze Email Headers",
+			ShortDesc: "Checks SPF and DKIM in email headers", Tags: []string{"SPF", "DKIM"},
+		},
+	}
+	index := newRelevanceIndex(patterns)
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "summarizing", want: "summarize summarize_git_diff"},
+		{query: "summarization of a git diff", want: "summarize_git_diff summarize"},
+		{query: "I want to explain some code", want: "explain_code"},
+		{query: "dkim", want: "analyze_email_headers"},
+		// Only stop words, or no known words: nothing matches.
+		{query: "what is the pattern for this", want: ""},
+		{query: "kubernetes", want: ""},
+		{query: "", want: ""},
+	}
+	for _, tt := range tests {
+		items, matches := index.search(tt.query)
+		var got []string
+		for _, item := range items {
+			dirName := item.(Pattern).DirName
+			got = append(got, dirName)
+			if _, ok := matches[dirName]; !ok {
+				t.Errorf("search(%q): no highlights for %s", tt.query, dirName)
+			}
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("search(%q) = %q, want %q", tt.query, strings.Join(got, " "), tt.want)
+		}
+	}
+
+	_, matches := index.search("explaining")
+	if title := matches["explain_code"].title; len(title) != len("Explain") || title[0] != 0 {
+		t.Errorf("search(%q) highlights %v in the title, want the runes of Explain", "explaining", title)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// diffOp is one step of an edit script: a token kept (' '), removed from
+// the old text ('-') or added by the new text ('+'). a and b are the
+// positions of the token, or of the insertion point, in the old and new
+// tokens.
+type diffOp struct {
+	kind byte
+	text string
+	a, b int
+}
+
+// maxDiffEdits bounds the edit distance diffTokens searches for. The search
+// keeps a frontier per edit, so its memory grows with the square of the
+// distance.
+const maxDiffEdits = 2000
+
+// errTooDifferent is returned by diffTokens for texts that need more than
+// maxDiffEdits edits.
+var errTooDifferent = fmt.Errorf("the files differ in more than %d places, too many to show", maxDiffEdits)
+
+// diffTokens returns the shortest edit script that turns a into b, using
+// Myers' algorithm.
+func diffTokens(a, b []string) ([]diffOp, error) {
+	// Common prefixes and suffixes are kept as is, which makes the search
+	// much cheaper for mostly equal texts.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{kind: ' ', text: a[i], a: i, b: i})
+	}
+	middle, err := myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range middle {
+		op.a += prefix
+		op.b += prefix
+		ops = append(ops, op)
+	}
+	for i := suffix; i > 0; i-- {
+		ops = append(ops, diffOp{kind: ' ', text: a[len(a)-i], a: len(a) - i, b: len(b) - i})
+	}
+	return ops, nil
+}
+
+func myers(a, b []string) ([]diffOp, error) {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil, nil
+	}
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	// trace[d] holds the frontier before step d, for diagonals -d to d
+	// only: the others are not reached yet.
+	var trace [][]int
+
+search:
+	for d := 0; d <= n+m; d++ {
+		if d > maxDiffEdits {
+			return nil, errTooDifferent
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk back from the end through the recorded frontiers.
+	var ops []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[d+k-1] < v[d+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[d+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{kind: ' ', text: a[x], a: x, b: y})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, diffOp{kind: '+', text: b[y], a: x, b: y})
+		} else {
+			x--
+			ops = append(ops, diffOp{kind: '-', text: a[x], a: x, b: y})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, diffOp{kind: ' ', text: a[x], a: x, b: y})
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops, nil
+}
+
+// splitLines splits text into lines, without a trailing empty line.
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+var wordToken = regexp.MustCompile(`\s+|[\p{L}\p{N}_]+|.`)
+
+// splitWords splits a line into words, runs of whitespace and punctuation,
+// so that joining the tokens gives the line back.
+func splitWords(line string) []string {
+	return wordToken.FindAllString(line, -1)
+}
+
+// unifiedDiff renders a line diff as a unified patch with the given number
+// of context lines.
+func unifiedDiff(oldName, newName string, ops []diffOp, context int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// A hunk runs from context lines before the first change to context
+		// lines after the last change that is not separated from the next
+		// one by more than twice the context.
+		start := max(i-context, 0)
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*context {
+				end = min(end+context, len(ops))
+				break
+			}
+			end = run
+		}
+
+		oldCount, newCount := 0, 0
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		oldStart, newStart := ops[start].a, ops[start].b
+		if oldCount > 0 {
+			oldStart++
+		}
+		if newCount > 0 {
+			newStart++
+		}
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, op := range ops[start:end] {
+			b.WriteByte(op.kind)
+			b.WriteString(op.text)
+			b.WriteByte('\n')
+		}
+		i = end
+	}
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// formatOps renders an edit script as "kind+text" tokens, for comparison.
+func formatOps(ops []diffOp) string {
+	parts := make([]string, len(ops))
+	for i, op := range ops {
+		parts[i] = string(op.kind) + op.text
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestDiffTokens(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{a: "", b: "", want: ""},
+		{a: "a b c", b: "a b c", want: " a  b  c"},
+		{a: "", b: "a b", want: "+a +b"},
+		{a: "a b", b: "", want: "-a -b"},
+		{a: "a b c", b: "a x c", want: " a -b +x  c"},
+		{a: "a b c d", b: "a c d e", want: " a -b  c  d +e"},
+		{a: "x a b", b: "a b y", want: "-x  a  b +y"},
+		{a: "a b c a b b a", b: "c b a b a c", want: "-a -b  c +b  a  b -b  a +c"},
+	}
+	for _, tt := range tests {
+		a, b := strings.Fields(tt.a), strings.Fields(tt.b)
+		ops, err := diffTokens(a, b)
+		if err != nil {
+			t.Errorf("diffTokens(%q, %q): %v", tt.a, tt.b, err)
+			continue
+		}
+		if got := formatOps(ops); got != tt.want {
+			t.Errorf("diffTokens(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+
+		// The script must turn a into b, with positions that agree with it.
+		var oldTokens, newTokens []string
+		for _, op := range ops {
+			if op.kind != '+' {
+				if op.a >= len(a) || a[op.a] != op.text {
+					t.Errorf("diffTokens(%q, %q): %c%s has old position %d", tt.a, tt.b, op.kind, op.text, op.a)
+				}
+				oldTokens = append(oldTokens, op.text)
+			}
+			if op.kind != '-' {
+				if op.b >= len(b) || b[op.b] != op.text {
+					t.Errorf("diffTokens(%q, %q): %c%s has new position %d", tt.a, tt.b, op.kind, op.text, op.b)
+				}
+				newTokens = append(newTokens, op.text)
+			}
+		}
+		if strings.Join(oldTokens, " ") != tt.a || strings.Join(newTokens, " ") != tt.b {
+			t.Errorf("diffTokens(%q, %q) rebuilds %q and %q", tt.a, tt.b, oldTokens, newTokens)
+		}
+	}
+}
+
+func TestDiffTokensTooDifferent(t *testing.T) {
+	var a, b []string
+	for i := 0; i <= maxDiffEdits/2; i++ {
+		a = append(a, "a"+strconv.Itoa(i))
+		b = append(b, "b"+strconv.Itoa(i))
+	}
+	if _, err := diffTokens(a, b); !errors.Is(err, errTooDifferent) {
+		t.Errorf("diffTokens of %d changed tokens: got %v, want errTooDifferent", len(a)+len(b), err)
+	}
+	// A long common prefix and suffix do not count against the limit.
+	same := make([]string, 3*maxDiffEdits)
+	for i := range same {
+		same[i] = strconv.Itoa(i)
+	}
+	changed := append([]string(nil), same...)
+	changed[maxDiffEdits] = "x"
+	if _, err := diffTokens(same, changed); err != nil {
+		t.Errorf("diffTokens with one change: %v", err)
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// execute starts the given steps one after another, each once the previous
+// one has succeeded, and switches to the output pane.
+func (m model) execute(steps []Invocation) (tea.Model, tea.Cmd) {
+	if m.printMode {
+		return m.print(steps, " && ", "")
+	}
+	m.fanout = false
+	return m.startExecution(steps)
+}
+
+// executeFanout runs the given steps concurrently, at most
+// Config.Concurrency at a time, and switches to the output pane.
+func (m model) executeFanout(steps []Invocation) (tea.Model, tea.Cmd) {
+	if m.printMode {
+		return m.print(steps, " & ", " & wait")
+	}
+	// Steps beyond the concurrency limit start later; read the input now so
+	// that they all get the same text.
+	if len(steps) > 0 && steps[0].Input != nil {
+		if data, err := steps[0].Input.Read(); err == nil {
+			snapshot := snapshotSource{InputSource: steps[0].Input, data: data}
+			for i := range steps {
+				steps[i].Input = snapshot
+			}
+		}
+	}
+	m.fanout = true
+	return m.startExecution(steps)
+}
+
+// print records the steps as a single shell command line, joined by sep and
+// followed by suffix, and quits so that main can write it to stdout. The
+// output paths are resolved as they would be for a run, and the command
+// starts by creating their directories.
+func (m model) print(steps []Invocation, sep, suffix string) (tea.Model, tea.Cmd) {
+	steps = m.resolvePrintedSinks(steps)
+
+	var dirs []string
+	seen := map[string]bool{}
+	commands := make([]string, len(steps))
+	for i, step := range steps {
+		commands[i] = step.String()
+		for _, sink := range step.Sinks {
+			if dir := filepath.Dir(sink.Path); dir != "." && !seen[dir] {
+				seen[dir] = true
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	m.printed = strings.Join(commands, sep)
+	if len(steps) > 1 {
+		m.printed += suffix
+	}
+	if len(dirs) > 0 {
+		if len(steps) > 1 {
+			m.printed = "(" + m.printed + ")"
+		}
+		m.printed = shellJoin(append([]string{"mkdir", "-p"}, dirs...)) + " && " + m.printed
+	}
+	return m, tea.Quit
+}
+
+// resolvePrintedSinks expands the output paths of the steps like a run
+// would. Inputs that cannot be read now, piped stdin and the outputs of
+// earlier chain steps, expand {hash} and {slug} to placeholders. A step
+// reading the output of an earlier one is pointed at its resolved path.
+func (m model) resolvePrintedSinks(steps []Invocation) []Invocation {
+	resolved := make([]Invocation, len(steps))
+	renamed := map[string]string{}
+	for i, step := range steps {
+		if source, ok := step.Input.(FileSource); ok && renamed[source.Path] != "" {
+			source.Path = renamed[source.Path]
+			step.Input = source
+		}
+
+		var input []byte
+		readable := step.Input != nil && step.Input.Kind() != InputStdin
+		if readable {
+			var err error
+			input, err = step.Input.Read()
+			readable = err == nil
+		}
+		sinks := make([]OutputSink, len(step.Sinks))
+		for j, sink := range step.Sinks {
+			if readable {
+				sinks[j] = OutputSink{Kind: sink.Kind, Path: uniquePath(expandInputFields(sink.Path, input))}
+			} else {
+				sinks[j] = OutputSink{Kind: sink.Kind, Path: uniquePath(expandUnreadInput(sink.Path))}
+			}
+			renamed[sink.Path] = sinks[j].Path
+		}
+		step.Sinks = sinks
+		resolved[i] = step
+	}
+	return resolved
+}
+
+func (m model) startExecution(steps []Invocation) (tea.Model, tea.Cmd) {
+	m.steps = steps
+	m.runs = nil
+	m.inputErr = nil
+	m.focus = 0
+	m.state = "executing"
+	m.resizeViewport()
+	m.viewport.SetContent("")
+	m.viewport.GotoTop()
+	return m, m.startSteps()
+}
+
+// startSteps starts pending steps while there is capacity: one at a time for
+// chains, stopping at the first failure, and up to the concurrency limit for
+// fan-outs.
+func (m *model) startSteps() tea.Cmd {
+	limit := 1
+	if m.fanout {
+		limit = max(m.config.Concurrency, 1)
+	}
+
+	var cmds []tea.Cmd
+	for len(m.runs) < len(m.steps) && m.activeRuns() < limit {
+		if !m.fanout && len(m.runs) > 0 && m.runs[len(m.runs)-1].err != nil {
+			break
+		}
+		r, cmd := startRun(chainInput(m.steps[len(m.runs)], m.steps, m.runs))
+		m.runs = append(m.runs, r)
+		cmds = append(cmds, cmd)
+		if r.done {
+			m.recordRun(r)
+		}
+	}
+
+	if !m.fanout {
+		m.focus = len(m.runs) - 1
+	}
+	m.run = m.runs[m.focus]
+	m.refreshOutput()
+	return tea.Batch(cmds...)
+}
+
+// resizeViewport fits the output pane below the command and status lines.
+func (m *model) resizeViewport() {
+	height := m.height - 8
+	if m.fanout {
+		height -= len(m.steps) - 1
+	}
+	m.viewport.Height = max(height, 1)
+}
+
+func (m *model) activeRuns() int {
+	active := 0
+	for _, r := range m.runs {
+		if !r.done {
+			active++
+		}
+	}
+	return active
+}
+
+// updateRun handles the events sent by running commands.
+func (m model) updateRun(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case runOutputMsg:
+		if !m.ownsRun(msg.run) {
+			return m, nil
+		}
+		msg.run.output.WriteString(msg.chunk)
+		m.refreshOutput()
+		return m, msg.run.listen()
+	case runFinishedMsg:
+		if !m.ownsRun(msg.run) {
+			return m, nil
+		}
+		msg.run.done = true
+		msg.run.finished = time.Now()
+		msg.run.err = msg.err
+		m.recordRun(msg.run)
+		m.indexOutputs(msg.run)
+		return m, m.startSteps()
+	case runTickMsg:
+		if m.ownsRun(msg.run) && !msg.run.done {
+			return m, msg.run.tick()
+		}
+	}
+	return m, nil
+}
+
+func (m *model) ownsRun(r *run) bool {
+	for _, owned := range m.runs {
+		if owned == r {
+			return true
+		}
+	}
+	return false
+}
+
+// executionDone reports whether no step is running and none will be started.
+func (m *model) executionDone() bool {
+	if m.activeRuns() > 0 {
+		return false
+	}
+	if len(m.runs) == len(m.steps) {
+		return true
+	}
+	return !m.fanout && m.runs[len(m.runs)-1].err != nil
+}
+
+// refreshOutput shows the output of the focused fan-out step, or of all
+// started chain steps, in the viewport.
+func (m *model) refreshOutput() {
+	var b strings.Builder
+	for i, r := range m.runs {
+		if m.fanout && i != m.focus {
+			continue
+		}
+		if len(m.steps) > 1 && !m.fanout {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString(statusStyle.Render(fmt.Sprintf("── %d/%d %s ──", i+1, len(m.steps), r.invocation.Pattern.DirName)))
+			b.WriteString("\n")
+		}
+		b.WriteString(r.output.String())
+	}
+
+	atBottom := m.viewport.AtBottom()
+	m.viewport.SetContent(b.String())
+	if atBottom {
+		m.viewport.GotoBottom()
+	}
+}
+
+// updateExecuting handles key presses while the output pane is shown.
+func (m model) updateExecuting(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		for _, r := range m.runs {
+			r.cancel()
+		}
+		return m, tea.Quit
+	case "x":
+		m.run.cancel()
+		return m, nil
+	case "X":
+		for _, r := range m.runs {
+			r.cancel()
+		}
+		if m.fanout {
+			// Runs that have not started yet are dropped.
+			m.steps = m.steps[:len(m.runs)]
+		}
+		return m, nil
+	case "tab", "shift+tab":
+		if m.fanout && len(m.runs) > 0 {
+			delta := 1
+			if msg.String() == "shift+tab" {
+				delta = len(m.runs) - 1
+			}
+			m.focus = (m.focus + delta) % len(m.runs)
+			m.run = m.runs[m.focus]
+			m.refreshOutput()
+			m.viewport.GotoTop()
+			return m, nil
+		}
+	case "q":
+		if m.executionDone() {
+			return m, tea.Quit
+		}
+	case "v":
+		if m.run.done {
+			m.viewRun(m.run)
+			return m, nil
+		}
+	case "a":
+		if m.run.done {
+			m.openPostRun()
+			return m, nil
+		}
+	case "esc", "enter":
+		if m.executionDone() {
+			m.state = "selecting"
+			m.list.SetItems(m.filteredItems)
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+// viewRun opens the output of a finished run in the viewer, read from its
+// output file when it was saved.
+func (m *model) viewRun(r *run) {
+	for _, sink := range r.invocation.Sinks {
+		if m.openOutputFile(sink.Path) == nil {
+			return
+		}
+	}
+	m.openViewer(r.invocation.Pattern.DirName, r.output.String())
+}
+
+func (m model) executionView() string {
+	status := m.run.status()
+	if len(m.steps) > 1 && !m.fanout {
+		status = fmt.Sprintf("Step %d/%d (%s): %s", len(m.runs), len(m.steps), m.run.invocation.Pattern.DirName, status)
+	}
+
+	help := "↑/↓ to scroll, x to cancel, ctrl+c to quit"
+	if m.fanout {
+		help = "↑/↓ to scroll, tab to switch pattern, x to cancel it, X to cancel all, ctrl+c to quit"
+	}
+	if m.executionDone() {
+		help = "↑/↓ to scroll, v to view rendered, a for actions, esc to return to patterns, q to quit"
+		if m.fanout {
+			help = "↑/↓ to scroll, tab to switch pattern, v to view rendered, a for actions, esc to return to patterns, q to quit"
+		}
+	}
+
+	sections := []string{commandStyle.Render(m.run.invocation.String())}
+	if m.fanout {
+		sections = append(sections, m.fanoutProgress())
+	} else {
+		sections = append(sections, statusStyle.Render(status))
+	}
+	sections = append(sections, m.viewport.View())
+	if m.inputErr != nil {
+		sections = append(sections, errorStyle.Render(m.inputErr.Error()))
+	}
+	sections = append(sections, help)
+
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
+}
+
+// fanoutProgress renders one status line per fan-out step.
+func (m model) fanoutProgress() string {
+	lines := make([]string, len(m.steps))
+	for i, step := range m.steps {
+		status := "Queued"
+		if i < len(m.runs) {
+			status = m.runs[i].status()
+		}
+		if i == m.focus {
+			lines[i] = statusStyle.Render(fmt.Sprintf("› %s: %s", step.Pattern.DirName, status))
+		} else {
+			lines[i] = fmt.Sprintf("  %s: %s", step.Pattern.DirName, status)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// facetSidebarWidth is the width of the facet sidebar, including its border.
+const facetSidebarWidth = 36
+
+var (
+	chipStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FAFAFA")).
+			Background(lipgloss.Color("#7D56F4")).
+			Padding(0, 1)
+
+	currentChipStyle = chipStyle.
+				Background(lipgloss.Color("#EE6FF8")).
+				Bold(true)
+
+	facetCursorStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#EE6FF8")).
+				Bold(true)
+
+	facetHeaderStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#7D56F4")).
+				Bold(true)
+
+	facetDimStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#626262"))
+
+	sidebarStyle = lipgloss.NewStyle().
+			BorderStyle(lipgloss.NormalBorder()).
+			BorderRight(true).
+			BorderForeground(lipgloss.Color("#626262")).
+			PaddingRight(1)
+)
+
+// Facet kinds.
+const (
+	FacetTag      = "tag"
+	FacetCategory = "cat"
+)
+
+// facetValue is a value that patterns can be filtered on.
+type facetValue struct {
+	kind  string
+	value string
+}
+
+// facetFilter filters the patterns on several tags and categories at once.
+// Selected values are kept in the order they were chosen, which is the
+// order of the chips.
+type facetFilter struct {
+	values   []facetValue
+	selected []facetValue
+	// matchAll requires every selected value (AND) instead of any (OR).
+	matchAll bool
+	cursor   int
+	// chip is the index of the chip that backspace removes.
+	chip int
+	// listFocused moves the focus from the sidebar to the pattern list.
+	listFocused bool
+}
+
+func newFacetFilter(tags, categories []string) *facetFilter {
+	f := &facetFilter{matchAll: true}
+	for _, tag := range tags {
+		f.values = append(f.values, facetValue{kind: FacetTag, value: tag})
+	}
+	for _, category := range categories {
+		f.values = append(f.values, facetValue{kind: FacetCategory, value: category})
+	}
+	return f
+}
+
+func (f *facetFilter) active() bool {
+	return len(f.selected) > 0
+}
+
+func (f *facetFilter) isSelected(v facetValue) bool {
+	for _, s := range f.selected {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *facetFilter) toggle(v facetValue) {
+	for i, s := range f.selected {
+		if s == v {
+			f.removeChip(i)
+			return
+		}
+	}
+	f.selected = append(f.selected, v)
+	f.chip = len(f.selected) - 1
+}
+
+// removeChip drops the chip at index i. The chip after it, or else the one
+// before, becomes the current chip.
+func (f *facetFilter) removeChip(i int) {
+	if i < 0 || i >= len(f.selected) {
+		return
+	}
+	f.selected = append(f.selected[:i], f.selected[i+1:]...)
+	f.chip = max(min(f.chip, len(f.selected)-1), 0)
+}
+
+// removeCurrent drops the current chip, the most recently added one unless
+// another was chosen with moveChip.
+func (f *facetFilter) removeCurrent() {
+	f.removeChip(f.chip)
+}
+
+// moveChip makes the chip delta places away the current one.
+func (f *facetFilter) moveChip(delta int) {
+	f.chip = max(min(f.chip+delta, len(f.selected)-1), 0)
+}
+
+func (f *facetFilter) clear() {
+	f.selected = nil
+	f.chip = 0
+}
+
+// has reports whether the pattern carries the value.
+func (v facetValue) has(pattern Pattern) bool {
+	values := pattern.Tags
+	if v.kind == FacetCategory {
+		values = pattern.Categories
+	}
+	for _, value := range values {
+		if value == v.value {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *facetFilter) matches(pattern Pattern) bool {
+	if !f.active() {
+		return true
+	}
+	for _, v := range f.selected {
+		if v.has(pattern) != f.matchAll {
+			// A missing value fails AND; a present one satisfies OR.
+			return !f.matchAll
+		}
+	}
+	return f.matchAll
+}
+
+// apply returns the patterns that pass the filter.
+func (f *facetFilter) apply(patterns []list.Item) []list.Item {
+	if !f.active() {
+		return patterns
+	}
+	var filtered []list.Item
+	for _, item := range patterns {
+		if f.matches(item.(Pattern)) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// counts returns, for each facet value, how many patterns it would match:
+// among the current results when combining with AND, since it would narrow
+// them further, and among all patterns when combining with OR.
+func (f *facetFilter) counts(all, results []list.Item) map[facetValue]int {
+	base := all
+	if f.matchAll {
+		base = results
+	}
+	counts := make(map[facetValue]int, len(f.values))
+	for _, item := range base {
+		pattern := item.(Pattern)
+		for _, tag := range pattern.Tags {
+			counts[facetValue{kind: FacetTag, value: tag}]++
+		}
+		for _, category := range pattern.Categories {
+			counts[facetValue{kind: FacetCategory, value: category}]++
+		}
+	}
+	return counts
+}
+
+// chips renders the selected values as chips, with the current one
+// highlighted.
+func (f *facetFilter) chips() string {
+	if !f.active() {
+		return ""
+	}
+	parts := make([]string, 0, len(f.selected)+1)
+	for i, v := range f.selected {
+		style := chipStyle
+		if i == f.chip && len(f.selected) > 1 {
+			style = currentChipStyle
+		}
+		parts = append(parts, style.Render(fmt.Sprintf("%s: %s ×", v.kind, v.value)))
+	}
+	mode := "any of"
+	if f.matchAll {
+		mode = "all of"
+	}
+	return facetDimStyle.Render(mode+" ") + strings.Join(parts, " ")
+}
+
+// openFacets shows the facet sidebar next to the pattern list.
+func (m *model) openFacets() {
+	m.state = "facets"
+	m.facets.listFocused = false
+	m.highlights.set(nil)
+	m.textInput.SetValue("")
+	m.applyFacets()
+	m.resizeList()
+}
+
+// applyFacets filters the pattern list with the selected facets.
+func (m *model) applyFacets() {
+	m.filteredItems = m.facets.apply(m.allPatterns)
+	m.list.SetItems(m.filteredItems)
+	m.currentSearch = savedSearch{}
+	if m.facets.active() {
+		m.currentSearch = savedSearch{Filter: "Facets", MatchAll: m.facets.matchAll}
+		for _, v := range m.facets.selected {
+			m.currentSearch.Facets = append(m.currentSearch.Facets, savedFacet{Kind: v.kind, Value: v.value})
+		}
+	}
+}
+
+// resizeList fits the pattern list next to the sidebar while it is shown.
+func (m *model) resizeList() {
+	width := m.viewport.Width
+	if width == 0 {
+		width = m.config.Width
+	}
+	height := m.height - 6
+	if m.state == "facets" {
+		width -= facetSidebarWidth
+		if m.facets.active() {
+			height -= 2
+		}
+	}
+	m.list.SetSize(max(width, 10), max(height, 3))
+}
+
+// updateFacets handles key presses in the facet view.
+func (m model) updateFacets(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	f := m.facets
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		// The facet filter stays applied to the pattern list.
+		m.state = "selecting"
+		m.resizeList()
+		return m, nil
+	case "tab", "shift+tab":
+		f.listFocused = !f.listFocused
+		return m, nil
+	case "m":
+		f.matchAll = !f.matchAll
+		m.applyFacets()
+		return m, nil
+	case "backspace", "delete":
+		f.removeCurrent()
+		m.applyFacets()
+		m.resizeList()
+		return m, nil
+	case "X":
+		f.clear()
+		m.applyFacets()
+		m.resizeList()
+		return m, nil
+	}
+
+	if f.listFocused {
+		if msg.String() == "enter" {
+			if pattern, ok := m.list.SelectedItem().(Pattern); ok {
+				m.state = "selecting"
+				m.resizeList()
+				m.choosePattern(pattern)
+			}
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.list, cmd = m.list.Update(msg)
+		return m, cmd
+	}
+
+	if len(f.values) == 0 {
+		return m, nil
+	}
+	switch msg.String() {
+	case "up", "k":
+		f.cursor = max(f.cursor-1, 0)
+	case "down", "j":
+		f.cursor = max(min(f.cursor+1, len(f.values)-1), 0)
+	case "pgup":
+		f.cursor = max(f.cursor-10, 0)
+	case "pgdown":
+		f.cursor = max(min(f.cursor+10, len(f.values)-1), 0)
+	case "left", "h":
+		f.moveChip(-1)
+	case "right", "l":
+		f.moveChip(1)
+	case "home", "g":
+		f.cursor = 0
+	case "end", "G":
+		f.cursor = max(len(f.values)-1, 0)
+	case " ", "enter":
+		if f.cursor >= 0 && f.cursor < len(f.values) {
+			f.toggle(f.values[f.cursor])
+			m.applyFacets()
+			m.resizeList()
+		}
+	}
+	return m, nil
+}
+
+// sidebarView renders the facet values with their counts, scrolled so that
+// the cursor is visible.
+func (m model) sidebarView(height int) string {
+	f := m.facets
+	counts := f.counts(m.allPatterns, m.filteredItems)
+
+	var lines []string
+	cursorLine := 0
+	kind := ""
+	for i, v := range f.values {
+		if v.kind != kind {
+			kind = v.kind
+			header := "Tags"
+			if kind == FacetCategory {
+				header = "Categories"
+			}
+			lines = append(lines, facetHeaderStyle.Render(header))
+		}
+		box := "[ ]"
+		if f.isSelected(v) {
+			box = "[x]"
+		}
+		line := fmt.Sprintf("%s %s (%d)", box, v.value, counts[v])
+		if width := facetSidebarWidth - 4; lipgloss.Width(line) > width {
+			line = string([]rune(line)[:width-1]) + "…"
+		}
+		switch {
+		case i == f.cursor && !f.listFocused:
+			line = facetCursorStyle.Render(line)
+			cursorLine = len(lines)
+		case counts[v] == 0 && !f.isSelected(v):
+			line = facetDimStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+
+	height = max(height, 1)
+	start := 0
+	if cursorLine >= height {
+		start = cursorLine - height + 1
+	}
+	end := min(start+height, len(lines))
+	return sidebarStyle.Width(facetSidebarWidth - 2).Height(height).Render(strings.Join(lines[start:end], "\n"))
+}
+
+func (m model) facetsView() string {
+	mode := "OR"
+	if m.facets.matchAll {
+		mode = "AND"
+	}
+	help := fmt.Sprintf("space to toggle, m to switch AND/OR (now %s), ←/→ to pick a chip, backspace to remove it, X to clear, tab to switch to the list, esc to close:", mode)
+	if m.facets.listFocused {
+		help = "↑/↓ to navigate, enter to select, tab to switch to the facets, esc to close:"
+	}
+
+	right := m.list.View()
+	if chips := m.facets.chips(); chips != "" {
+		right = lipgloss.JoinVertical(lipgloss.Left, chips, "", right)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left,
+		help,
+		lipgloss.JoinHorizontal(lipgloss.Top, m.sidebarView(lipgloss.Height(right)), " ", right),
+	)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// provenance records what produced a saved output.
+type provenance struct {
+	Pattern    Pattern
+	Model      string
+	Started    time.Time
+	Duration   time.Duration
+	Err        error
+	InputHash  string
+	InputBytes int
+}
+
+// runStatus classifies how a run ended.
+func runStatus(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, errRunCancelled):
+		return "cancelled"
+	case errors.Is(err, errRunTimedOut):
+		return "timed_out"
+	}
+	return "failed"
+}
+
+// exitCode returns the exit code of a finished run, or -1 if the process did
+// not exit normally.
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr):
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// frontMatter renders the provenance as a YAML front matter block.
+func (p provenance) frontMatter() string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	field := func(name, value string) {
+		fmt.Fprintf(&b, "%s: %s\n", name, value)
+	}
+	list := func(name string, values []string) {
+		quoted := make([]string, len(values))
+		for i, v := range values {
+			quoted[i] = strconv.Quote(v)
+		}
+		field(name, "["+strings.Join(quoted, ", ")+"]")
+	}
+
+	field("pattern", strconv.Quote(p.Pattern.DirName))
+	field("friendly_name", strconv.Quote(p.Pattern.FriendlyName))
+	list("categories", p.Pattern.Categories)
+	list("tags", p.Pattern.Tags)
+	field("model", strconv.Quote(p.Model))
+	field("timestamp", p.Started.Format(time.RFC3339))
+	field("duration_seconds", strconv.FormatFloat(p.Duration.Seconds(), 'f', 1, 64))
+	field("status", runStatus(p.Err))
+	field("exit_status", strconv.Itoa(exitCode(p.Err)))
+	field("input_sha256", strconv.Quote(p.InputHash))
+	field("input_bytes", strconv.Itoa(p.InputBytes))
+	b.WriteString("---\n\n")
+	return b.String()
+}
+
+// provenanceKeys are written into every front matter block by frontMatter
+// and identify a block as FabricForge's own.
+var provenanceKeys = []string{"pattern", "timestamp", "status", "input_sha256"}
+
+// stripFrontMatter removes a leading front matter block written by
+// FabricForge from the content. Other leading blocks, such as a "---" rule
+// or front matter the model produced, are part of the output and kept.
+func stripFrontMatter(content []byte) []byte {
+	text := string(content)
+	if !strings.HasPrefix(text, "---\n") {
+		return content
+	}
+	end := strings.Index(text[4:], "\n---\n")
+	if end == -1 {
+		return content
+	}
+	fields := frontMatterFields(content)
+	for _, key := range provenanceKeys {
+		if _, ok := fields[key]; !ok {
+			return content
+		}
+	}
+	return []byte(strings.TrimPrefix(text[4+end+5:], "\n"))
+}
+
+// frontMatterFields returns the top-level scalar fields of the front matter
+// at the start of content, with quoted values unquoted.
+func frontMatterFields(content []byte) map[string]string {
+	fields := map[string]string{}
+	text := string(content)
+	if !strings.HasPrefix(text, "---\n") {
+		return fields
+	}
+	end := strings.Index(text[4:], "\n---\n")
+	if end == -1 {
+		return fields
+	}
+	for _, line := range strings.Split(text[4:4+end], "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		fields[strings.TrimSpace(name)] = value
+	}
+	return fields
+}
+
+// prependFrontMatter rewrites the Markdown file at path with the front
+// matter at the top. Other files are left alone.
+func prependFrontMatter(path, frontMatter string) error {
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".md" && ext != ".markdown" {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append([]byte(frontMatter), content...), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStripFrontMatter(t *testing.T) {
+	header := provenance{
+		Pattern:   Pattern{DirName: "summarize", FriendlyName: "Summarize", Tags: []string{"summary"}},
+		Model:     "gpt-4o",
+		Started:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Duration:  3 * time.Second,
+		InputHash: "abc123",
+	}.frontMatter()
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "provenance", content: header + "# Summary\n", want: "# Summary\n"},
+		{name: "no front matter", content: "# Summary\n", want: "# Summary\n"},
+		{
+			name:    "foreign front matter",
+			content: "---\ntitle: Notes\nlayout: post\n---\n\nBody\n",
+			want:    "---\ntitle: Notes\nlayout: post\n---\n\nBody\n",
+		},
+		{
+			name:    "missing provenance key",
+			content: "---\npattern: \"summarize\"\ntimestamp: 2024-05-01T12:00:00Z\nstatus: success\n---\nBody\n",
+			want:    "---\npattern: \"summarize\"\ntimestamp: 2024-05-01T12:00:00Z\nstatus: success\n---\nBody\n",
+		},
+		{
+			name:    "unterminated",
+			content: "---\npattern: \"summarize\"\nBody\n",
+			want:    "---\npattern: \"summarize\"\nBody\n",
+		},
+		{name: "horizontal rule", content: "Intro\n---\nMore\n", want: "Intro\n---\nMore\n"},
+	}
+	for _, tt := range tests {
+		if got := string(stripFrontMatter([]byte(tt.content))); got != tt.want {
+			t.Errorf("%s: stripFrontMatter = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
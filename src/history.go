@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// historySnapshotLimit is the largest input kept verbatim in the history.
+// Larger inputs are only recorded by hash and size.
+const historySnapshotLimit = 64 * 1024
+
+// historyEntry records one execution.
+type historyEntry struct {
+	Time         time.Time     `json:"time"`
+	Pattern      string        `json:"pattern"`
+	FriendlyName string        `json:"friendly_name"`
+	Command      string        `json:"command"`
+	Options      FabricOptions `json:"options"`
+	Model        string        `json:"model,omitempty"`
+	InputKind    string        `json:"input_kind"`
+	InputLabel   string        `json:"input_label"`
+	InputPath    string        `json:"input_path,omitempty"`
+	InputHash    string        `json:"input_sha256"`
+	InputBytes   int           `json:"input_bytes"`
+	Input        *string       `json:"input,omitempty"`
+	Outputs      []string      `json:"outputs,omitempty"`
+	Duration     float64       `json:"duration_seconds"`
+	Status       string        `json:"status"`
+	ExitCode     int           `json:"exit_code"`
+	Error        string        `json:"error,omitempty"`
+}
+
+func (e historyEntry) Title() string {
+	mark := "✓"
+	if e.Status != "success" {
+		mark = "✗ " + e.Status
+	}
+	return fmt.Sprintf("%s  %s  %s  %.1fs", e.Time.Local().Format("2006-01-02 15:04"), e.Pattern, mark, e.Duration)
+}
+
+func (e historyEntry) Description() string {
+	if len(e.Outputs) > 0 {
+		return fmt.Sprintf("%s → %s", e.InputLabel, strings.Join(e.Outputs, ", "))
+	}
+	return e.InputLabel
+}
+
+func (e historyEntry) FilterValue() string {
+	return strings.Join([]string{e.Pattern, e.FriendlyName, e.Status, e.InputLabel, e.Model, e.Time.Local().Format("2006-01-02")}, " ")
+}
+
+// inputSource returns a source that reproduces the input of the entry: the
+// same file for file input, otherwise the recorded snapshot.
+func (e historyEntry) inputSource() (InputSource, error) {
+	if e.InputKind == InputFile && e.InputPath != "" {
+		return FileSource{Path: e.InputPath}, nil
+	}
+	if e.Input == nil {
+		return nil, fmt.Errorf("the input of this run was too large to be kept")
+	}
+	return TextSource{Text: *e.Input}, nil
+}
+
+// newHistoryEntry describes a finished run.
+func newHistoryEntry(r *run) historyEntry {
+	inv := r.invocation
+	entry := historyEntry{
+		Time:         r.started,
+		Pattern:      inv.Pattern.DirName,
+		FriendlyName: inv.Pattern.FriendlyName,
+		Command:      inv.String(),
+		Options:      inv.Options,
+		Model:        inv.Model,
+		InputHash:    inputHash(r.input),
+		InputBytes:   len(r.input),
+		Duration:     r.elapsed().Seconds(),
+		Status:       runStatus(r.err),
+		ExitCode:     exitCode(r.err),
+	}
+	if inv.Input != nil {
+		entry.InputKind = inv.Input.Kind()
+		entry.InputLabel = inv.Input.Label()
+		if file, ok := inv.Input.(FileSource); ok {
+			entry.InputPath = file.Path
+		}
+	}
+	if len(r.input) <= historySnapshotLimit {
+		input := string(r.input)
+		entry.Input = &input
+	}
+	for _, sink := range inv.Sinks {
+		entry.Outputs = append(entry.Outputs, sink.Path)
+	}
+	if r.err != nil {
+		entry.Error = r.err.Error()
+	}
+	return entry
+}
+
+// historyRerun holds the options and input of a history entry while it is
+// re-run through the confirmation screen. They apply to that pattern only,
+// so that neither the saved options nor the current input change.
+type historyRerun struct {
+	pattern string
+	options FabricOptions
+	input   InputSource
+}
+
+// patternOptions returns the options the pattern runs with: those of the
+// history entry being re-run, or the saved ones.
+func (m *model) patternOptions(dirName string) FabricOptions {
+	if m.rerun != nil && m.rerun.pattern == dirName {
+		return m.rerun.options
+	}
+	return m.options.get(dirName)
+}
+
+// setPatternOptions changes the options of the pattern, saving them unless
+// they belong to the history entry being re-run.
+func (m *model) setPatternOptions(dirName string, options FabricOptions) error {
+	if m.rerun != nil && m.rerun.pattern == dirName {
+		m.rerun.options = options
+		return nil
+	}
+	return m.options.set(dirName, options)
+}
+
+// runInput returns the input the pattern runs with: that of the history
+// entry being re-run, or the current one.
+func (m *model) runInput(dirName string) InputSource {
+	if m.rerun != nil && m.rerun.pattern == dirName && m.rerun.input != nil {
+		return m.rerun.input
+	}
+	return m.inputSource
+}
+
+// historyStore appends executions to a JSON lines file in the user config
+// directory.
+type historyStore struct {
+	path string
+}
+
+func newHistoryStore() *historyStore {
+	path, _ := userConfigPath("history.jsonl")
+	return &historyStore{path: path}
+}
+
+func (h *historyStore) add(entry historyEntry) error {
+	if h.path == "" {
+		return nil
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// load returns all entries, newest first. Lines that cannot be parsed are
+// skipped.
+func (h *historyStore) load() ([]historyEntry, error) {
+	if h.path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(h.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Lines are read whole, however long: a command with a large inline
+	// input must not stop the rest of the history from loading.
+	var entries []historyEntry
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		var entry historyEntry
+		if len(bytes.TrimSpace(line)) > 0 && json.Unmarshal(line, &entry) == nil {
+			entries = append(entries, entry)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// recordRun appends a finished run to the history.
+func (m *model) recordRun(r *run) {
+	if err := m.history.add(newHistoryEntry(r)); err != nil {
+		m.inputErr = fmt.Errorf("saving history: %w", err)
+	}
+}
+
+// openHistory shows the history browser.
+func (m *model) openHistory() {
+	entries, err := m.history.load()
+	m.inputErr = err
+	items := make([]list.Item, len(entries))
+	for i, entry := range entries {
+		items[i] = entry
+	}
+	m.state = "history"
+	m.list.ResetFilter()
+	m.list.SetItems(items)
+	m.list.Select(0)
+}
+
+// updateHistory handles key presses in the history browser. Keys go to the
+// list's own filter while it is being edited.
+func (m model) updateHistory(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.list.SettingFilter() {
+		var cmd tea.Cmd
+		m.list, cmd = m.list.Update(msg)
+		return m, cmd
+	}
+
+	entry, selected := m.list.SelectedItem().(historyEntry)
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		if m.list.IsFiltered() {
+			m.list.ResetFilter()
+			return m, nil
+		}
+		m.inputErr = nil
+		m.state = "selecting"
+		m.list.SetItems(m.filteredItems)
+		return m, nil
+	case "o":
+		if selected {
+			m.viewHistoryEntry(entry)
+		}
+		return m, nil
+	case "d":
+		if selected {
+			if len(entry.Outputs) == 0 {
+				m.inputErr = fmt.Errorf("this run has no saved output to compare")
+			} else {
+				m.markForDiff(entry.Outputs[0])
+			}
+		}
+		return m, nil
+	case "r", "enter":
+		if !selected {
+			return m, nil
+		}
+		pattern, ok := m.findPattern(entry.Pattern)
+		if !ok {
+			m.inputErr = fmt.Errorf("pattern %q is no longer available", entry.Pattern)
+			return m, nil
+		}
+		source, err := entry.inputSource()
+		if err != nil {
+			m.inputErr = err
+			return m, nil
+		}
+		rerun := &historyRerun{pattern: pattern.DirName, options: entry.Options, input: source}
+		m.list.ResetFilter()
+		if msg.String() == "r" {
+			m.rerun = rerun
+			m.selected = pattern
+			return m.execute([]Invocation{m.buildFabricCommand(pattern)})
+		}
+		// Enter goes through the confirmation screen to allow edits.
+		m.chooseRerun(pattern, rerun)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+// viewHistoryEntry shows the details and the saved output of an entry in
+// the viewer.
+func (m *model) viewHistoryEntry(entry historyEntry) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", entry.Pattern)
+	fmt.Fprintf(&b, "- **Command:** `%s`\n", entry.Command)
+	fmt.Fprintf(&b, "- **Started:** %s\n", entry.Time.Local().Format(time.RFC1123))
+	fmt.Fprintf(&b, "- **Status:** %s (exit code %d) after %.1fs\n", entry.Status, entry.ExitCode, entry.Duration)
+	if entry.Error != "" {
+		fmt.Fprintf(&b, "- **Error:** %s\n", entry.Error)
+	}
+	fmt.Fprintf(&b, "- **Input:** %s, %d bytes, sha256 `%s`\n", entry.InputLabel, entry.InputBytes, entry.InputHash)
+	for _, output := range entry.Outputs {
+		fmt.Fprintf(&b, "\n---\n\n")
+		content, err := os.ReadFile(output)
+		if err != nil {
+			fmt.Fprintf(&b, "> %s\n", err)
+			continue
+		}
+		b.Write(stripFrontMatter(content))
+		b.WriteString("\n")
+	}
+	m.openViewer(fmt.Sprintf("%s run of %s", entry.Time.Local().Format("2006-01-02 15:04"), entry.Pattern), b.String())
+}
+
+// findPattern looks up a loaded pattern by dir_name.
+func (m *model) findPattern(dirName string) (Pattern, bool) {
+	for _, item := range m.allPatterns {
+		if pattern := item.(Pattern); pattern.DirName == dirName {
+			return pattern, true
+		}
+	}
+	return Pattern{}, false
+}
+
+func (m model) historyView() string {
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		"Run history (enter to edit and re-run, r to re-run, o to open, d to compare, / to filter, esc to go back):",
+		m.list.View(),
+	)
+	if hint := m.diffHint(); hint != "" {
+		content = lipgloss.JoinVertical(lipgloss.Left, content, hint)
+	}
+	if m.inputErr != nil {
+		content = lipgloss.JoinVertical(lipgloss.Left, errorStyle.Render(m.inputErr.Error()), content)
+	}
+	return content
+}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestChoosingPatternDropsRerun(t *testing.T) {
+	m := testModel(t)
+	pattern := m.allPatterns[0].(Pattern)
+	m.chooseRerun(pattern, &historyRerun{
+		pattern: pattern.DirName,
+		options: FabricOptions{Model: "old-model"},
+		input:   TextSource{Text: "old input"},
+	})
+	if got := m.patternOptions(pattern.DirName).Model; got != "old-model" {
+		t.Fatalf("re-run options have model %q, want old-model", got)
+	}
+
+	// Back out and pick the same pattern from the list.
+	m.state = "selecting"
+	m.list.SetItems(m.allPatterns)
+	m, _ = press(m, "enter")
+	if m.rerun != nil {
+		t.Error("choosing a pattern from the list kept the history entry")
+	}
+	if got := m.patternOptions(pattern.DirName).Model; got != "" {
+		t.Errorf("options have model %q, want the saved options", got)
+	}
+	if _, ok := m.runInput(pattern.DirName).(ClipboardSource); !ok {
+		t.Errorf("input is %T, want the current input", m.runInput(pattern.DirName))
+	}
+}
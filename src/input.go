@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Input source kinds, as used by the INPUT_SOURCE setting.
+const (
+	InputClipboard = "clipboard"
+	InputFile      = "file"
+	InputStdin     = "stdin"
+	InputText      = "text"
+)
+
+// InputSource supplies the text that is piped into fabric.
+type InputSource interface {
+	// Kind returns one of the Input* constants.
+	Kind() string
+	// Label is a short human readable description of the source.
+	Label() string
+	// ShellCommand returns a shell command that writes the input to stdout,
+	// or an empty string if the input is passed on the process stdin.
+	ShellCommand() string
+	// Read returns the full input.
+	Read() ([]byte, error)
+}
+
+// ClipboardSource reads the system clipboard through an external tool.
+type ClipboardSource struct {
+	Tool []string
+}
+
+func (s ClipboardSource) Kind() string { return InputClipboard }
+
+func (s ClipboardSource) Label() string {
+	if len(s.Tool) == 0 {
+		return "Clipboard (no clipboard tool found)"
+	}
+	return fmt.Sprintf("Clipboard (%s)", s.Tool[0])
+}
+
+func (s ClipboardSource) ShellCommand() string {
+	return shellJoin(s.Tool)
+}
+
+func (s ClipboardSource) Read() ([]byte, error) {
+	if len(s.Tool) == 0 {
+		return nil, fmt.Errorf("no clipboard tool found (install wl-clipboard, xclip or xsel)")
+	}
+	return exec.Command(s.Tool[0], s.Tool[1:]...).Output()
+}
+
+// FileSource reads the input from a file on disk. SkipFrontMatter drops the
+// provenance front matter of a saved output, so that it is not fed to the
+// next step of a chain.
+type FileSource struct {
+	Path            string
+	SkipFrontMatter bool
+}
+
+func (s FileSource) Kind() string         { return InputFile }
+func (s FileSource) Label() string        { return fmt.Sprintf("File (%s)", s.Path) }
+func (s FileSource) ShellCommand() string { return "cat " + shellQuote(s.Path) }
+func (s FileSource) Read() ([]byte, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil || !s.SkipFrontMatter {
+		return data, err
+	}
+	return stripFrontMatter(data), nil
+}
+
+// StdinSource reads the input that was piped into FabricForge itself.
+type StdinSource struct {
+	once sync.Once
+	data []byte
+	err  error
+}
+
+func (s *StdinSource) Kind() string         { return InputStdin }
+func (s *StdinSource) Label() string        { return "Standard input" }
+func (s *StdinSource) ShellCommand() string { return "" }
+func (s *StdinSource) Read() ([]byte, error) {
+	s.once.Do(func() {
+		s.data, s.err = io.ReadAll(os.Stdin)
+	})
+	return s.data, s.err
+}
+
+// TextSource uses a literal string as the input.
+type TextSource struct {
+	Text string
+}
+
+func (s TextSource) Kind() string { return InputText }
+
+func (s TextSource) Label() string {
+	text := strings.ReplaceAll(s.Text, "\n", " ")
+	if len(text) > 30 {
+		text = text[:30] + "…"
+	}
+	return fmt.Sprintf("Text (%q)", text)
+}
+
+func (s TextSource) ShellCommand() string { return "printf '%s' " + shellQuote(s.Text) }
+func (s TextSource) Read() ([]byte, error) {
+	return []byte(s.Text), nil
+}
+
+// snapshotSource replays input read once from another source, so that
+// several runs get the same bytes even if the source changes meanwhile.
+type snapshotSource struct {
+	InputSource
+	data []byte
+}
+
+func (s snapshotSource) Read() ([]byte, error) {
+	return s.data, nil
+}
+
+// newInputSource builds the input source of the given kind. The value is the
+// file path for InputFile and the literal text for InputText.
+func newInputSource(kind, value string) (InputSource, error) {
+	switch strings.ToLower(kind) {
+	case "", InputClipboard:
+		return ClipboardSource{Tool: detectClipboardTool()}, nil
+	case InputFile:
+		if value == "" {
+			return nil, fmt.Errorf("input source %q needs a file path", kind)
+		}
+		return FileSource{Path: value}, nil
+	case InputStdin:
+		return &StdinSource{}, nil
+	case InputText:
+		return TextSource{Text: value}, nil
+	}
+	return nil, fmt.Errorf("unknown input source %q", kind)
+}
+
+// detectClipboardTool returns the command used to read the clipboard on this
+// system, or nil if none of the supported tools is installed.
+func detectClipboardTool() []string {
+	var candidates [][]string
+	if runtime.GOOS == "darwin" {
+		candidates = append(candidates, []string{"pbpaste"})
+	}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		candidates = append(candidates, []string{"wl-paste", "--no-newline"})
+	}
+	candidates = append(candidates,
+		[]string{"xclip", "-selection", "clipboard", "-o"},
+		[]string{"xsel", "--clipboard", "--output"},
+		[]string{"pbpaste"},
+	)
+	for _, candidate := range candidates {
+		if _, err := exec.LookPath(candidate[0]); err == nil {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// stdinIsPiped reports whether something other than a terminal is connected
+// to stdin.
+func stdinIsPiped() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice == 0
+}
+
+// shellQuote quotes s for safe use as a single POSIX shell word.
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:,+@%", r))
+	}) == -1 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
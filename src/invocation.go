@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Output sink kinds.
+const (
+	// SinkTee writes the output to a file while still showing it.
+	SinkTee = "tee"
+	// SinkFile writes the output only to a file.
+	SinkFile = "file"
+)
+
+// OutputSink is a destination for the standard output of an invocation.
+type OutputSink struct {
+	Kind string
+	Path string
+}
+
+// Invocation describes a single program run for a pattern: the program and
+// its arguments, where its input comes from and where its output goes. It is
+// executed directly, without a shell. A non-zero Timeout kills the program
+// once it has run that long.
+type Invocation struct {
+	Pattern Pattern
+	Program string
+	Args    []string
+	Input   InputSource
+	Sinks   []OutputSink
+	Timeout time.Duration
+
+	// Options are the fabric options the invocation was built from and Model
+	// the model the pattern runs with, if known. FrontMatter adds provenance
+	// front matter to Markdown output files.
+	Options     FabricOptions
+	Model       string
+	FrontMatter bool
+
+	// Chat, when set, sends the pattern to a model API directly instead of
+	// running Program.
+	Chat *ChatRequest
+}
+
+// executor returns what carries out the invocation.
+func (inv Invocation) executor() executor {
+	if inv.Chat != nil {
+		return &chatExecutor{req: inv.Chat}
+	}
+	return newProcessExecutor(inv.Program, inv.Args)
+}
+
+// String renders the invocation as an equivalent, properly quoted shell
+// command line for display.
+func (inv Invocation) String() string {
+	var parts []string
+	if inv.Input != nil {
+		if input := inv.Input.ShellCommand(); input != "" {
+			parts = append(parts, input)
+		}
+	}
+	if inv.Chat != nil {
+		parts = append(parts, inv.Chat.String())
+	} else {
+		parts = append(parts, shellJoin(append([]string{inv.Program}, inv.Args...)))
+	}
+
+	command := strings.Join(parts, " | ")
+	for _, sink := range inv.Sinks {
+		switch sink.Kind {
+		case SinkTee:
+			command += " | tee " + shellQuote(sink.Path)
+		case SinkFile:
+			command += " > " + shellQuote(sink.Path)
+		}
+	}
+	return command
+}
+
+// resolveSinks returns the sinks with the input dependent fields of their
+// paths expanded.
+func resolveSinks(sinks []OutputSink, input []byte) []OutputSink {
+	resolved := make([]OutputSink, len(sinks))
+	for i, sink := range sinks {
+		sink.Path = expandInputFields(sink.Path, input)
+		resolved[i] = sink
+	}
+	return resolved
+}
+
+// openSinks creates the sink files and returns the writer that should
+// receive the program's standard output, given the writer used for display.
+// No existing file is overwritten: a sink whose path is taken gets a
+// numbered one instead, recorded in inv.Sinks. The returned closer must be
+// called once the program has exited.
+func (inv *Invocation) openSinks(display io.Writer) (io.Writer, func() error, error) {
+	writers := []io.Writer{}
+	var files []*os.File
+	closeAll := func() error {
+		var firstErr error
+		for _, f := range files {
+			if err := f.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+
+	showOutput := true
+	for i, sink := range inv.Sinks {
+		if dir := filepath.Dir(sink.Path); dir != "" {
+			if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+				closeAll()
+				return nil, nil, fmt.Errorf("creating output directory: %w", err)
+			}
+		}
+		f, path, err := createUnique(sink.Path)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("creating output file: %w", err)
+		}
+		inv.Sinks[i].Path = path
+		files = append(files, f)
+		writers = append(writers, f)
+		if sink.Kind == SinkFile {
+			showOutput = false
+		}
+	}
+	if showOutput {
+		writers = append(writers, display)
+	}
+
+	return io.MultiWriter(writers...), closeAll, nil
+}
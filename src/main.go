@@ -1,48 +1,98 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
-	"os/exec"
+	"path/filepath"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/joho/godotenv"
 )
 
 func main() {
-	err := godotenv.Load()
+	if len(os.Args) > 1 && os.Args[1] == "widget" {
+		if err := printWidget(os.Stdout, os.Args[2:]); err != nil {
+			fail("%v\n", err)
+		}
+		return
+	}
+
+	printMode := flag.Bool("print", false, "print the selected command to stdout instead of running it")
+	flag.Parse()
+
+	envDir, err := loadEnv()
 	if err != nil {
-		fmt.Println("Error loading .env file")
-		os.Exit(1)
+		fail("Error loading .env file\n")
 	}
 
 	config := loadConfig()
+	config.resolvePaths(envDir)
 	patterns, err := loadPatterns(config.MetadataPath)
 	if err != nil {
-		fmt.Printf("Error loading patterns: %v\n", err)
-		os.Exit(1)
+		fail("Error loading patterns: %v\n", err)
+	}
+
+	sourceKind := config.InputSource
+	if sourceKind == "" && stdinIsPiped() {
+		sourceKind = InputStdin
+	}
+	source, err := newInputSource(sourceKind, config.InputFile)
+	if err != nil {
+		fail("Error configuring input source: %v\n", err)
 	}
 
-	m := initialModel(patterns, config)
+	m := initialModel(patterns, config, source)
+	m.printMode = *printMode
 
-	p := tea.NewProgram(m, tea.WithAltScreen())
+	options := []tea.ProgramOption{tea.WithAltScreen()}
+	if *printMode {
+		// Draw the UI on the terminal so that stdout only carries the command.
+		tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+		if err != nil {
+			fail("Error opening terminal: %v\n", err)
+		}
+		defer tty.Close()
+		options = append(options, tea.WithInputTTY(), tea.WithOutput(tty))
+	}
+
+	p := tea.NewProgram(m, options...)
 	finalModel, err := p.Run()
 	if err != nil {
-		fmt.Printf("Error running program: %v", err)
-		os.Exit(1)
-	}
-
-	// Execute the selected command only if confirmed
-	finalM, ok := finalModel.(model)
-	if ok && finalM.selectedCmd != "" && finalM.state == "executing" {
-		fmt.Printf("Executing command: %s\n", finalM.selectedCmd)
-		cmd := exec.Command("sh", "-c", finalM.selectedCmd)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		err := cmd.Run()
-		if err != nil {
-			fmt.Printf("Error executing command: %v\n", err)
-			os.Exit(1)
+		fail("Error running program: %v", err)
+	}
+
+	if finalM, ok := finalModel.(model); ok && finalM.printed != "" {
+		if config.Backend == BackendNative {
+			fmt.Fprintln(os.Stderr, "FabricForge: BACKEND=native has no command line; printing the equivalent fabric command instead.")
 		}
+		fmt.Println(finalM.printed)
 	}
-}
\ No newline at end of file
+}
+
+// loadEnv loads the .env file from the working directory, falling back to
+// the directory of the executable so that FabricForge can be started from
+// anywhere (e.g. by a shell widget). It returns the directory the file was
+// loaded from.
+func loadEnv() (string, error) {
+	err := godotenv.Load()
+	if err == nil {
+		return ".", nil
+	}
+
+	exe, exeErr := os.Executable()
+	if exeErr != nil {
+		return "", err
+	}
+	dir := filepath.Dir(exe)
+	if godotenv.Load(filepath.Join(dir, ".env")) != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// fail prints the message to stderr and exits.
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	mdHeadingStyles = []lipgloss.Style{
+		lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FAFAFA")).Background(lipgloss.Color("#7D56F4")),
+		lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#7D56F4")),
+		lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#AD8CFF")),
+		lipgloss.NewStyle().Bold(true),
+	}
+	mdBoldStyle   = lipgloss.NewStyle().Bold(true)
+	mdItalicStyle = lipgloss.NewStyle().Italic(true)
+	mdCodeStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF87D7"))
+	mdLinkStyle   = lipgloss.NewStyle().Underline(true).Foreground(lipgloss.Color("#5FAFFF"))
+	mdBlockStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#00FF00"))
+	mdQuoteStyle  = lipgloss.NewStyle().Italic(true).Foreground(lipgloss.Color("#A8A8A8"))
+	mdRuleStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#585858"))
+)
+
+var (
+	mdHeadingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdListPattern    = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	mdRulePattern    = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	mdFencePattern   = regexp.MustCompile("^\\s*(```|~~~)")
+)
+
+// mdLine is one line of rendered Markdown: the styled text for display and
+// the plain text for searching.
+type mdLine struct {
+	plain  string
+	styled string
+}
+
+// mdHeading is a section heading and the rendered line it starts on.
+type mdHeading struct {
+	line  int
+	level int
+	title string
+}
+
+// markdownDoc is a Markdown document rendered for the terminal.
+type markdownDoc struct {
+	lines    []mdLine
+	headings []mdHeading
+}
+
+// renderMarkdown renders the subset of Markdown that patterns produce
+// (headings, paragraphs, lists, quotes, rules, code blocks and inline
+// emphasis) to lines at most width cells wide.
+func renderMarkdown(src string, width int) markdownDoc {
+	width = max(width, 20)
+	var doc markdownDoc
+	var paragraph []string
+	inCode := false
+
+	blank := func() {
+		if n := len(doc.lines); n > 0 && doc.lines[n-1].plain != "" {
+			doc.lines = append(doc.lines, mdLine{})
+		}
+	}
+	flush := func() {
+		if len(paragraph) > 0 {
+			text := strings.Join(paragraph, " ")
+			doc.lines = append(doc.lines, wrapSpans(parseInline(text, lipgloss.NewStyle()), width, "", "")...)
+			paragraph = nil
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		line = strings.ReplaceAll(line, "\t", "    ")
+		if mdFencePattern.MatchString(line) {
+			flush()
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			doc.lines = append(doc.lines, mdLine{plain: "  " + line, styled: "  " + mdBlockStyle.Render(line)})
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+			blank()
+		case mdHeadingPattern.MatchString(trimmed):
+			flush()
+			blank()
+			match := mdHeadingPattern.FindStringSubmatch(trimmed)
+			level := len(match[1])
+			style := mdHeadingStyles[min(level, len(mdHeadingStyles))-1]
+			doc.headings = append(doc.headings, mdHeading{line: len(doc.lines), level: level, title: match[2]})
+			doc.lines = append(doc.lines, wrapSpans(parseInline(match[2], style), width, "", "")...)
+			doc.lines = append(doc.lines, mdLine{})
+		case mdRulePattern.MatchString(line):
+			flush()
+			rule := strings.Repeat("─", width)
+			doc.lines = append(doc.lines, mdLine{plain: rule, styled: mdRuleStyle.Render(rule)})
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			text := strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))
+			doc.lines = append(doc.lines, wrapSpans(parseInline(text, mdQuoteStyle), width, "│ ", "│ ")...)
+		case mdListPattern.MatchString(line):
+			flush()
+			match := mdListPattern.FindStringSubmatch(line)
+			indent := strings.Repeat(" ", len(match[1]))
+			bullet := "• "
+			if match[2][0] >= '0' && match[2][0] <= '9' {
+				bullet = match[2] + " "
+			}
+			hanging := indent + strings.Repeat(" ", lipgloss.Width(bullet))
+			doc.lines = append(doc.lines, wrapSpans(parseInline(match[3], lipgloss.NewStyle()), width, indent+bullet, hanging)...)
+		case strings.HasPrefix(trimmed, "|"):
+			// Tables are shown as written.
+			flush()
+			doc.lines = append(doc.lines, mdLine{plain: line, styled: line})
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+
+	for len(doc.lines) > 0 && doc.lines[len(doc.lines)-1].plain == "" {
+		doc.lines = doc.lines[:len(doc.lines)-1]
+	}
+	return doc
+}
+
+// mdSpan is a run of text with one style.
+type mdSpan struct {
+	text  string
+	style lipgloss.Style
+}
+
+// parseInline splits text into spans for bold, italic, code and links, on
+// top of the base style.
+func parseInline(text string, base lipgloss.Style) []mdSpan {
+	var spans []mdSpan
+	var plain strings.Builder
+	emit := func(text string, style lipgloss.Style) {
+		if plain.Len() > 0 {
+			spans = append(spans, mdSpan{text: plain.String(), style: base})
+			plain.Reset()
+		}
+		spans = append(spans, mdSpan{text: text, style: style})
+	}
+
+	for i := 0; i < len(text); {
+		rest := text[i:]
+		switch {
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			if end := strings.Index(rest[2:], rest[:2]); end > 0 {
+				emit(rest[2:2+end], base.Inherit(mdBoldStyle).Bold(true))
+				i += 4 + end
+				continue
+			}
+		case rest[0] == '*':
+			if end := strings.IndexByte(rest[1:], '*'); end > 0 {
+				emit(rest[1:1+end], base.Inherit(mdItalicStyle).Italic(true))
+				i += 2 + end
+				continue
+			}
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end >= 0 {
+				emit(rest[1:1+end], mdCodeStyle)
+				i += 2 + end
+				continue
+			}
+		case rest[0] == '[':
+			if close := strings.Index(rest, "]("); close > 0 {
+				if end := strings.IndexByte(rest[close:], ')'); end > 0 {
+					emit(rest[1:close], mdLinkStyle)
+					i += close + end + 1
+					continue
+				}
+			}
+		}
+		plain.WriteByte(text[i])
+		i++
+	}
+	if plain.Len() > 0 {
+		spans = append(spans, mdSpan{text: plain.String(), style: base})
+	}
+	return spans
+}
+
+// wrapSpans word-wraps the spans to width. The first line starts with
+// prefix and the following ones with hanging.
+func wrapSpans(spans []mdSpan, width int, prefix, hanging string) []mdLine {
+	type word struct {
+		text  string
+		style lipgloss.Style
+		glued bool // no space before the word
+	}
+	var words []word
+	afterSpace := true
+	for _, span := range spans {
+		if span.text == "" {
+			continue
+		}
+		glued := !afterSpace && span.text[0] != ' '
+		for i, field := range strings.Fields(span.text) {
+			words = append(words, word{text: field, style: span.style, glued: i == 0 && glued})
+		}
+		afterSpace = span.text[len(span.text)-1] == ' '
+	}
+
+	var lines []mdLine
+	plain, styled := prefix, prefix
+	empty := true
+	for _, w := range words {
+		sep := " "
+		if w.glued || empty {
+			sep = ""
+		}
+		if !empty && !w.glued && lipgloss.Width(plain)+1+lipgloss.Width(w.text) > width {
+			lines = append(lines, mdLine{plain: plain, styled: styled})
+			plain, styled = hanging, hanging
+			sep = ""
+		}
+		plain += sep + w.text
+		styled += sep + w.style.Render(w.text)
+		empty = false
+	}
+	if !empty {
+		lines = append(lines, mdLine{plain: plain, styled: styled})
+	}
+	return lines
+}
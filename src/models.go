@@ -3,18 +3,31 @@ package main
 import (
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 )
 
 type Pattern struct {
-	DirName      string   `json:"dir_name"`
-	FriendlyName string   `json:"friendly_name"`
-	ShortDesc    string   `json:"short_description"`
-	Categories   []string `json:"categories"`
-	Tags         []string `json:"tags"`
+	DirName             string   `json:"dir_name"`
+	FriendlyName        string   `json:"friendly_name"`
+	ShortDesc           string   `json:"short_description"`
+	LongDesc            string   `json:"description"`
+	Categories          []string `json:"categories"`
+	Tags                []string `json:"tags"`
+	RelatedPatterns     []string `json:"related_patterns"`
+	CharacterCount      int      `json:"character_count"`
+	EstimatedTokenCount int      `json:"estimated_token_count"`
+	UsageExample        string   `json:"usage_example"`
+
+	// Optional execution defaults from the pattern metadata
+	PreferredModel  string   `json:"preferred_model,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"top_p,omitempty"`
+	OutputExtension string   `json:"output_extension,omitempty"`
 }
 
 type PatternList struct {
@@ -30,22 +43,67 @@ func (f FilterOption) Title() string       { return f.Name }
 func (f FilterOption) Description() string { return f.Desc }
 func (f FilterOption) FilterValue() string { return f.Name }
 
+type inputSourceItem struct {
+	kind  string
+	title string
+	desc  string
+}
+
+func (i inputSourceItem) Title() string       { return i.title }
+func (i inputSourceItem) Description() string { return i.desc }
+func (i inputSourceItem) FilterValue() string { return i.title }
+
 type model struct {
 	list           list.Model
 	textInput      textinput.Model
 	allPatterns    []list.Item
+	loadedPatterns []list.Item
 	filteredItems  []list.Item
 	alphaSort      bool
 	sortByDirName  bool
 	config         Config
 	state          string
-	selectedCmd    string
-	confirmItems   []list.Item
+	invocation     Invocation
 	filterOptions  []list.Item
 	currentFilter  string
 	allTags        []string
 	allCategories  []string
 	allDirectories []string
+	inputSource    InputSource
+	inputItems     []list.Item
+	inputKind      string
+	inputErr       error
+	selected       Pattern
+	viewport       viewport.Model
+	marks          *patternMarks
+	highlights     *patternHighlights
+	suggestions    *patternSuggestions
+	suggestRequest int
+	facets         *facetFilter
+	steps          []Invocation
+	runs           []*run
+	run            *run
+	fanout         bool
+	focus          int
+	height         int
+	options        *optionsStore
+	optionsForm    optionsForm
+	variables      []string
+	variablesForm  variablesForm
+	group          variableGroup
+	rerun          *historyRerun
+	printMode      bool
+	printed        string
+	history        *historyStore
+	viewer         outputViewer
+	actionStatus   string
+	diffBase       string
+	comparison     comparison
+	index          *searchIndex
+	snippets       *snippetCache
+	searches       *searchStore
+	currentSearch  savedSearch
+	relevance      *relevanceIndex
 }
 
 func (i Pattern) Title() string {
@@ -55,6 +113,14 @@ func (i Pattern) Title() string {
 func (i Pattern) Description() string { return i.ShortDesc }
 func (i Pattern) FilterValue() string { return i.FriendlyName }
 
+// outputExt returns the file extension for the pattern's saved output.
+func (i Pattern) outputExt() string {
+	if i.OutputExtension == "" {
+		return ".md"
+	}
+	return "." + strings.TrimPrefix(i.OutputExtension, ".")
+}
+
 type confirmItem struct {
 	title string
 	desc  string
@@ -64,60 +130,140 @@ func (i confirmItem) Title() string       { return i.title }
 func (i confirmItem) Description() string { return i.desc }
 func (i confirmItem) FilterValue() string { return i.title }
 
-func initialModel(patterns []list.Item, config Config) model {
+func initialModel(patterns []list.Item, config Config, source InputSource) model {
 	ti := textinput.New()
 	ti.Placeholder = config.Placeholder
 	ti.Focus()
 
-	l := list.New(patterns, list.NewDefaultDelegate(), config.Width, config.Height)
-	
+	marks := &patternMarks{}
+	highlights := &patternHighlights{}
+	suggestions := &patternSuggestions{}
+	l := list.New(patterns, newPatternDelegate(marks, highlights, suggestions), config.Width, config.Height)
+	// Update decides when q and esc quit; the list would also quit while a
+	// search box has focus.
+	l.DisableQuitKeybindings()
 
-	confirmItems := []list.Item{
-		confirmItem{title: "Yes", desc: "Execute the command"},
-		confirmItem{title: "No", desc: "Cancel and return to pattern selection"},
+	inputItems := []list.Item{
+		inputSourceItem{kind: InputClipboard, title: "Clipboard", desc: "Read the system clipboard"},
+		inputSourceItem{kind: InputFile, title: "File", desc: "Read a file from disk"},
+		inputSourceItem{kind: InputText, title: "Text", desc: "Type the input directly"},
+	}
+	if stdinIsPiped() {
+		inputItems = append(inputItems, inputSourceItem{kind: InputStdin, title: "Standard input", desc: "Use the text piped into FabricForge"})
 	}
 
 	filterOptions := []list.Item{
 		FilterOption{Name: "Global Search", Desc: "Search across all fields"},
+		FilterOption{Name: "Describe", Desc: "Describe what you want and get the most relevant patterns"},
 		FilterOption{Name: "Tags", Desc: "Filter by tags"},
 		FilterOption{Name: "Categories", Desc: "Filter by categories"},
 		FilterOption{Name: "Directories", Desc: "Filter by directory names"},
+		FilterOption{Name: "Facets", Desc: "Combine several tags and categories"},
 	}
 
 	allTags, allCategories, allDirectories := extractMetadata(patterns)
 
+	vp := viewport.New(config.Width, config.Height)
+	options, optionsErr := loadOptionsStore()
+
 	return model{
 		list:           l,
+		viewport:       vp,
+		marks:          marks,
+		highlights:     highlights,
+		suggestions:    suggestions,
+		facets:         newFacetFilter(allTags, allCategories),
+		relevance:      newRelevanceIndex(patterns),
+		height:         config.Height,
+		options:        options,
+		history:        newHistoryStore(),
+		searches:       loadSearchStore(),
 		textInput:      ti,
 		allPatterns:    patterns,
+		loadedPatterns: patterns,
 		filteredItems:  patterns,
 		alphaSort:      config.AlphaSort,
 		sortByDirName:  config.SortByDirName,
 		config:         config,
 		state:          "selecting",
-		confirmItems:   confirmItems,
 		filterOptions:  filterOptions,
 		currentFilter:  "Global Search",
 		allTags:        allTags,
 		allCategories:  allCategories,
 		allDirectories: allDirectories,
+		inputSource:    source,
+		inputItems:     inputItems,
+		inputErr:       optionsErr,
 	}
 }
 
-func (m *model) buildFabricCommand(pattern Pattern) string {
-	timestamp := time.Now().Format("2006-01-02T15:04:05-07:00")
-	outputFile := fmt.Sprintf("%s_%s_output.md", pattern.DirName, timestamp)
-
-	var command string
-	if m.config.StreamResults && m.config.OutputResults {
-		command = fmt.Sprintf("pbpaste | fabric --pattern %s | tee %s/%s", pattern.DirName, m.config.OutputDir, outputFile)
-	} else if m.config.OutputResults {
-		command = fmt.Sprintf("pbpaste | fabric --pattern %s > %s/%s", pattern.DirName, m.config.OutputDir, outputFile)
-	} else {
-		command = fmt.Sprintf("pbpaste | fabric --pattern %s", pattern.DirName)
+// confirmItems returns the choices offered on the confirmation screen.
+func (m *model) confirmItems() []list.Item {
+	items := []list.Item{
+		confirmItem{title: "Yes", desc: "Execute the command"},
+		confirmItem{title: "Input source", desc: m.runInput(m.selected.DirName).Label()},
+		confirmItem{title: "Options", desc: m.optionsSummary()},
+	}
+	if len(m.variables) > 0 {
+		items = append(items, confirmItem{title: "Variables", desc: variablesSummary(m.variables, m.patternOptions(m.selected.DirName).Variables)})
+	}
+	return append(items, confirmItem{title: "No", desc: "Cancel and return to pattern selection"})
+}
+
+// optionsSummary describes the fabric flags set for the selected pattern.
+func (m *model) optionsSummary() string {
+	options := m.patternOptions(m.selected.DirName)
+	options.Variables = nil
+	args := options.args()
+	if len(args) == 0 {
+		return "Fabric defaults"
+	}
+	return shellJoin(args)
+}
+
+func (m *model) buildFabricCommand(pattern Pattern) Invocation {
+	return m.buildFabricCommandWith(pattern, m.patternOptions(pattern.DirName))
+}
+
+// buildFabricCommandWith builds the invocation for the pattern using the
+// given fabric options.
+func (m *model) buildFabricCommandWith(pattern Pattern, options FabricOptions) Invocation {
+	chosen := options
+	options = options.withDefaults(pattern)
+	if options.Stream == nil {
+		options.Stream = &m.config.StreamResults
+	}
+	model := options.Model
+	if model == "" && m.config.Backend == BackendNative {
+		model = m.config.LLMModel
+	}
+	outputFile := outputFilePath(m.config.OutputTemplate, m.config.OutputDir, pattern, model, time.Now())
+
+	invocation := Invocation{
+		Pattern: pattern,
+		Program: "fabric",
+		Args:    append([]string{"--pattern", pattern.DirName}, options.args()...),
+		Input:   m.runInput(pattern.DirName),
+		Timeout: m.config.RunTimeout,
+
+		Options:     chosen,
+		Model:       model,
+		FrontMatter: m.config.FrontMatter,
+	}
+
+	if m.config.Backend == BackendNative && !m.printMode {
+		invocation.Chat = newChatRequest(m.config, pattern, options)
+	}
+
+	if m.config.OutputResults {
+		sink := OutputSink{Kind: SinkFile, Path: outputFile}
+		if options.streaming() {
+			sink.Kind = SinkTee
+		}
+		invocation.Sinks = append(invocation.Sinks, sink)
 	}
 
-	return command
+	return invocation
 }
 
 func extractMetadata(patterns []list.Item) ([]string, []string, []string) {
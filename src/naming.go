@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultOutputTemplate names output files when OUTPUT_FILE_TEMPLATE is not
+// set. The pattern's output extension is appended to the expanded template.
+const defaultOutputTemplate = "{pattern}_{date}_{time}_output"
+
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// sanitizePathPart makes s safe to use inside a single file name.
+func sanitizePathPart(s string) string {
+	return strings.Trim(unsafePathChars.ReplaceAllString(s, "-"), "-.")
+}
+
+// slugify turns s into a short lowercase slug.
+func slugify(s string, limit int) string {
+	slug := strings.ToLower(sanitizePathPart(strings.ReplaceAll(s, ".", " ")))
+	if len(slug) > limit {
+		slug = strings.TrimRight(slug[:limit], "-")
+	}
+	return slug
+}
+
+// outputFilePath expands the fields of the output file template that are
+// known before the input is read:
+//
+//	{pattern}  the pattern dir_name
+//	{name}     a slug of the friendly name
+//	{category} a slug of the first category
+//	{date}     the date as 2006-01-02
+//	{time}     the time as 150405
+//	{model}    the model, or "default"
+//
+// {hash} and {slug} depend on the input and are expanded by
+// expandInputFields once the input has been read. Slashes in the template
+// create subdirectories of dir.
+func outputFilePath(template, dir string, pattern Pattern, model string, now time.Time) string {
+	if template == "" {
+		template = defaultOutputTemplate
+	}
+
+	category := "uncategorized"
+	if len(pattern.Categories) > 0 {
+		category = slugify(pattern.Categories[0], 40)
+	}
+	if model == "" {
+		model = "default"
+	}
+
+	name := strings.NewReplacer(
+		"{pattern}", sanitizePathPart(pattern.DirName),
+		"{name}", slugify(pattern.FriendlyName, 40),
+		"{category}", category,
+		"{date}", now.Format("2006-01-02"),
+		"{time}", now.Format("150405"),
+		"{model}", sanitizePathPart(model),
+	).Replace(template)
+
+	return filepath.Join(dir, filepath.FromSlash(name)+pattern.outputExt())
+}
+
+// expandInputFields expands the {hash} (the first 8 hex digits of the
+// input's SHA-256) and {slug} (a slug of the first input line) fields.
+func expandInputFields(path string, input []byte) string {
+	if !strings.Contains(path, "{hash}") && !strings.Contains(path, "{slug}") {
+		return path
+	}
+
+	line, _, _ := strings.Cut(strings.TrimSpace(string(input)), "\n")
+	slug := slugify(line, 40)
+	if slug == "" {
+		slug = "empty"
+	}
+
+	return strings.NewReplacer(
+		"{hash}", inputHash(input)[:8],
+		"{slug}", slug,
+	).Replace(path)
+}
+
+// expandUnreadInput expands {hash} and {slug} when the input is not known
+// yet, as when printing a command that reads piped stdin.
+func expandUnreadInput(path string) string {
+	return strings.NewReplacer("{hash}", "unhashed", "{slug}", "input").Replace(path)
+}
+
+// inputHash returns the hex SHA-256 of the input.
+func inputHash(input []byte) string {
+	sum := sha256.Sum256(input)
+	return hex.EncodeToString(sum[:])
+}
+
+// createUnique creates a new file at path, or if one already exists there,
+// at the first of path-2, path-3, ... (before the extension) that is free.
+// It returns the file and the path it was created at. O_EXCL makes the
+// check and the creation one step, so concurrent runs never share a file.
+func createUnique(path string) (*os.File, string, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	candidate := path
+	for i := 2; ; i++ {
+		f, err := os.OpenFile(candidate, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if !os.IsExist(err) {
+			return f, candidate, err
+		}
+		candidate = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+}
+
+// uniquePath returns path, or if a file already exists there, the first of
+// path-2, path-3, ... (before the extension) that does not exist. It only
+// predicts the name createUnique would pick, for printed commands.
+func uniquePath(path string) string {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return path
+	}
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d%s", base, i, ext)
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUniquePath(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "summary.md")
+	want := []string{"summary.md", "summary-2.md", "summary-3.md"}
+	for _, name := range want {
+		// uniquePath predicts the name createUnique then takes.
+		predicted := uniquePath(path)
+		f, got, err := createUnique(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		if filepath.Base(got) != name || predicted != got {
+			t.Errorf("createUnique = %s and uniquePath = %s, want %s", got, predicted, name)
+		}
+	}
+
+	noExt := filepath.Join(dir, "notes")
+	if err := os.WriteFile(noExt, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := filepath.Base(uniquePath(noExt)); got != "notes-2" {
+		t.Errorf("uniquePath = %s, want notes-2", got)
+	}
+
+	if _, _, err := createUnique(filepath.Join(dir, "missing", "out.md")); err == nil {
+		t.Error("createUnique in a missing directory: want an error")
+	}
+}
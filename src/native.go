@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Backends that can run a pattern.
+const (
+	BackendFabric = "fabric"
+	BackendNative = "native"
+)
+
+// ChatRequest describes a pattern run against an OpenAI-compatible
+// chat-completions endpoint, without the fabric binary. UserPath is the
+// pattern's optional user.md, sent ahead of the input, and Context the name
+// of a fabric context, prepended to the system prompt. Sessions need
+// fabric's conversation store and are not supported.
+type ChatRequest struct {
+	URL         string
+	APIKey      string
+	Model       string
+	SystemPath  string
+	UserPath    string
+	Context     string
+	Session     string
+	Temperature *float64
+	TopP        *float64
+	Stream      bool
+	Language    string
+	Variables   map[string]string
+}
+
+// newChatRequest builds the request for the pattern from the native backend
+// settings and the fabric options.
+func newChatRequest(config Config, pattern Pattern, options FabricOptions) *ChatRequest {
+	req := &ChatRequest{
+		URL:        strings.TrimRight(config.LLMBaseURL, "/") + "/chat/completions",
+		APIKey:     config.LLMAPIKey,
+		Model:      options.Model,
+		SystemPath: filepath.Join(config.PatternsDir, pattern.DirName, "system.md"),
+		UserPath:   filepath.Join(config.PatternsDir, pattern.DirName, "user.md"),
+		Context:    options.Context,
+		Session:    options.Session,
+		Stream:     options.streaming(),
+		Language:   options.Language,
+		Variables:  options.Variables,
+	}
+	if req.Model == "" {
+		req.Model = config.LLMModel
+	}
+	if f, err := strconv.ParseFloat(options.Temperature, 64); err == nil {
+		req.Temperature = &f
+	}
+	if f, err := strconv.ParseFloat(options.TopP, 64); err == nil {
+		req.TopP = &f
+	}
+	return req
+}
+
+// fabricContextPath returns the file fabric keeps the named context in.
+func fabricContextPath(name string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "fabric", "contexts", name), nil
+}
+
+// check reports the options the native backend cannot honour.
+func (r *ChatRequest) check() error {
+	if r.Session != "" {
+		return fmt.Errorf("sessions need the fabric backend; clear the session option or set BACKEND=fabric")
+	}
+	if r.Context != "" {
+		path, err := fabricContextPath(r.Context)
+		if err != nil {
+			return fmt.Errorf("locating context %q: %w", r.Context, err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("context %q: %w", r.Context, err)
+		}
+	}
+	return nil
+}
+
+// String describes the request for the command preview.
+func (r *ChatRequest) String() string {
+	return fmt.Sprintf("POST %s (model %s, system prompt %s)", r.URL, r.Model, r.SystemPath)
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatCompletionRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Stream      bool          `json:"stream"`
+	Temperature *float64      `json:"temperature,omitempty"`
+	TopP        *float64      `json:"top_p,omitempty"`
+}
+
+type chatCompletionResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+		Delta   chatMessage `json:"delta"`
+	} `json:"choices"`
+}
+
+// body returns the JSON request body for the given input.
+func (r *ChatRequest) body(input []byte) ([]byte, error) {
+	if err := r.check(); err != nil {
+		return nil, err
+	}
+	system, err := os.ReadFile(r.SystemPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading pattern: %w", err)
+	}
+	prompt := applyVariables(string(system), r.Variables)
+	if r.Context != "" {
+		path, _ := fabricContextPath(r.Context)
+		background, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading context: %w", err)
+		}
+		prompt = string(background) + "\n" + prompt
+	}
+	if r.Language != "" {
+		prompt += fmt.Sprintf("\n\nPlease use the language '%s' for the output.", r.Language)
+	}
+
+	user := string(input)
+	if r.UserPath != "" {
+		prefix, err := os.ReadFile(r.UserPath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("reading pattern: %w", err)
+		}
+		if len(prefix) > 0 {
+			user = applyVariables(string(prefix), r.Variables) + "\n" + user
+		}
+	}
+
+	return json.Marshal(chatCompletionRequest{
+		Model: r.Model,
+		Messages: []chatMessage{
+			{Role: "system", Content: prompt},
+			{Role: "user", Content: user},
+		},
+		Stream:      r.Stream,
+		Temperature: r.Temperature,
+		TopP:        r.TopP,
+	})
+}
+
+// chatExecutor sends a ChatRequest and writes the reply to stdout as it
+// arrives.
+type chatExecutor struct {
+	req    *ChatRequest
+	cancel context.CancelFunc
+	done   chan error
+}
+
+func (e *chatExecutor) Start(stdin io.Reader, stdout, stderr io.Writer) error {
+	var input []byte
+	if stdin != nil {
+		var err error
+		if input, err = io.ReadAll(stdin); err != nil {
+			return err
+		}
+	}
+	body, err := e.req.body(input)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	e.done = make(chan error, 1)
+	go func() {
+		e.done <- e.send(ctx, body, stdout)
+		cancel()
+	}()
+	return nil
+}
+
+func (e *chatExecutor) Wait() error { return <-e.done }
+
+func (e *chatExecutor) Kill() error {
+	e.cancel()
+	return nil
+}
+
+func (e *chatExecutor) send(ctx context.Context, body []byte, stdout io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.req.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.req.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.req.APIKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if !e.req.Stream {
+		var completion chatCompletionResponse
+		if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+			return fmt.Errorf("decoding response: %w", err)
+		}
+		if len(completion.Choices) > 0 {
+			_, err = io.WriteString(stdout, completion.Choices[0].Message.Content+"\n")
+		}
+		return err
+	}
+
+	// Server-sent events: one "data: {json}" line per chunk, ending with
+	// "data: [DONE]".
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+		var chunk chatCompletionResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("decoding stream: %w", err)
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			if _, err := io.WriteString(stdout, chunk.Choices[0].Delta.Content); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(stdout, "\n")
+	return err
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChatExecutorStreams(t *testing.T) {
+	var got chatCompletionRequest
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, ": keep-alive\n\n")
+		io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n")
+		io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hello\"}}]}\n\n")
+		io.WriteString(w, "data:{\"choices\":[{\"delta\":{\"content\":\", world\"}}]}\n\n")
+		io.WriteString(w, "data: [DONE]\n\n")
+		io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"ignored\"}}]}\n\n")
+	}))
+	defer server.Close()
+
+	e := &chatExecutor{req: &ChatRequest{URL: server.URL, APIKey: "secret", Stream: true}}
+	body, _ := json.Marshal(chatCompletionRequest{Model: "m", Stream: true})
+	var out strings.Builder
+	if err := e.send(context.Background(), body, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "Hello, world\n" {
+		t.Errorf("output = %q, want %q", out.String(), "Hello, world\n")
+	}
+	if auth != "Bearer secret" || got.Model != "m" || !got.Stream {
+		t.Errorf("server got Authorization %q and body %+v", auth, got)
+	}
+}
+
+func TestChatExecutorWholeReply(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"Done."}}]}`)
+	}))
+	defer server.Close()
+
+	e := &chatExecutor{req: &ChatRequest{URL: server.URL}}
+	var out strings.Builder
+	if err := e.send(context.Background(), []byte("{}"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "Done.\n" {
+		t.Errorf("output = %q, want %q", out.String(), "Done.\n")
+	}
+}
+
+func TestChatExecutorErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"invalid api key"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	e := &chatExecutor{req: &ChatRequest{URL: server.URL, Stream: true}}
+	var out strings.Builder
+	err := e.send(context.Background(), []byte("{}"), &out)
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "invalid api key") {
+		t.Errorf("send = %v, want the status and the body", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("output = %q, want nothing", out.String())
+	}
+}
+
+func TestChatExecutorCancel(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"partial\"}}]}\n\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &chatExecutor{req: &ChatRequest{URL: server.URL, Stream: true}}
+	done := make(chan error, 1)
+	var out strings.Builder
+	go func() { done <- e.send(ctx, []byte("{}"), &out) }()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("send = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("send did not return after the context was cancelled")
+	}
+}
+
+func TestChatRequestBody(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := t.TempDir()
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(dir, "system.md"), "Write in a {{tone}} tone.")
+	write(filepath.Join(dir, "user.md"), "Topic: {{topic}}")
+	write(filepath.Join(home, ".config", "fabric", "contexts", "work"), "We sell bikes.")
+
+	req := &ChatRequest{
+		Model:      "m",
+		SystemPath: filepath.Join(dir, "system.md"),
+		UserPath:   filepath.Join(dir, "user.md"),
+		Context:    "work",
+		Language:   "fr",
+		Variables:  map[string]string{"tone": "dry", "topic": "gears"},
+	}
+	body, err := req.body([]byte("the input"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got chatCompletionRequest
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Messages) != 2 {
+		t.Fatalf("got %d messages, want 2", len(got.Messages))
+	}
+	system := "We sell bikes.\nWrite in a dry tone.\n\nPlease use the language 'fr' for the output."
+	if got.Messages[0].Content != system {
+		t.Errorf("system prompt = %q, want %q", got.Messages[0].Content, system)
+	}
+	if got.Messages[1].Content != "Topic: gears\nthe input" {
+		t.Errorf("user message = %q, want %q", got.Messages[1].Content, "Topic: gears\nthe input")
+	}
+
+	// Without user.md the input is sent as is.
+	req.UserPath = filepath.Join(dir, "missing.md")
+	body, err = req.body([]byte("the input"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Messages[1].Content != "the input" {
+		t.Errorf("user message = %q, want the input", got.Messages[1].Content)
+	}
+
+	for _, bad := range []*ChatRequest{
+		{SystemPath: req.SystemPath, Context: "missing"},
+		{SystemPath: req.SystemPath, Session: "chat"},
+	} {
+		if _, err := bad.body(nil); err == nil {
+			t.Errorf("body with context %q and session %q: want an error", bad.Context, bad.Session)
+		}
+	}
+}
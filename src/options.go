@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// FabricOptions holds the per-run fabric flags that can be set on the
+// confirmation screen.
+type FabricOptions struct {
+	Model       string `json:"model,omitempty"`
+	Temperature string `json:"temperature,omitempty"`
+	TopP        string `json:"top_p,omitempty"`
+	Stream      *bool  `json:"stream,omitempty"`
+	Session     string `json:"session,omitempty"`
+	Context     string `json:"context,omitempty"`
+	Language    string `json:"language,omitempty"`
+
+	// Variables fill the {{name}} placeholders of the pattern.
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+// args returns the fabric flags for the options that are set.
+func (o FabricOptions) args() []string {
+	var args []string
+	add := func(flag, value string) {
+		if value != "" {
+			args = append(args, flag, value)
+		}
+	}
+	add("--model", o.Model)
+	add("--temperature", o.Temperature)
+	add("--topp", o.TopP)
+	if o.streaming() {
+		args = append(args, "--stream")
+	}
+	add("--session", o.Session)
+	add("--context", o.Context)
+	add("--language", o.Language)
+	for _, name := range sortedKeys(o.Variables) {
+		if o.Variables[name] != "" {
+			args = append(args, fmt.Sprintf("--variable=%s:%s", name, o.Variables[name]))
+		}
+	}
+	return args
+}
+
+// streaming reports whether Stream is switched on. Stream is nil when it
+// follows STREAM_RESULTS.
+func (o FabricOptions) streaming() bool {
+	return o.Stream != nil && *o.Stream
+}
+
+// withDefaults fills the model, temperature and top_p left empty with the
+// defaults from the pattern metadata.
+func (o FabricOptions) withDefaults(pattern Pattern) FabricOptions {
+	if o.Model == "" {
+		o.Model = pattern.PreferredModel
+	}
+	if o.Temperature == "" && pattern.Temperature != nil {
+		o.Temperature = strconv.FormatFloat(*pattern.Temperature, 'g', -1, 64)
+	}
+	if o.TopP == "" && pattern.TopP != nil {
+		o.TopP = strconv.FormatFloat(*pattern.TopP, 'g', -1, 64)
+	}
+	return o
+}
+
+// validate checks that the numeric options are in range.
+func (o FabricOptions) validate() error {
+	check := func(name, value string, limit float64) error {
+		if value == "" {
+			return nil
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || f < 0 || f > limit {
+			return fmt.Errorf("%s must be a number between 0 and %g", name, limit)
+		}
+		return nil
+	}
+	if err := check("temperature", o.Temperature, 2); err != nil {
+		return err
+	}
+	return check("top_p", o.TopP, 1)
+}
+
+// optionsStore remembers the last used options per pattern dir_name.
+type optionsStore struct {
+	path    string
+	loadErr error
+	Options map[string]FabricOptions `json:"options"`
+}
+
+// loadOptionsStore reads the saved options. A file that cannot be read is
+// reported and left alone: the store starts empty and refuses to save.
+func loadOptionsStore() (*optionsStore, error) {
+	store := &optionsStore{Options: map[string]FabricOptions{}}
+	path, err := userConfigPath("options.json")
+	if err != nil {
+		return store, nil
+	}
+	store.path = path
+	if err := loadJSON(path, store); err != nil {
+		store.Options = map[string]FabricOptions{}
+		store.loadErr = fmt.Errorf("reading saved options %s: %w", path, err)
+		return store, store.loadErr
+	}
+	if store.Options == nil {
+		store.Options = map[string]FabricOptions{}
+	}
+	return store, nil
+}
+
+func (s *optionsStore) get(dirName string) FabricOptions {
+	return s.Options[dirName]
+}
+
+func (s *optionsStore) set(dirName string, options FabricOptions) error {
+	s.Options[dirName] = options
+	if s.loadErr != nil {
+		return fmt.Errorf("not saved, %w", s.loadErr)
+	}
+	if s.path == "" {
+		return nil
+	}
+	return saveJSON(s.path, s)
+}
+
+// Fields of the options form, in display order.
+const (
+	optionModel = iota
+	optionTemperature
+	optionTopP
+	optionStream
+	optionSession
+	optionContext
+	optionLanguage
+	optionCount
+)
+
+var optionLabels = [optionCount]string{"Model", "Temperature", "Top P", "Stream", "Session", "Context", "Language"}
+
+// optionsForm edits FabricOptions. Stream cycles between the default, on and
+// off; all other fields are text inputs. Empty fields show the pattern
+// defaults as placeholders.
+type optionsForm struct {
+	inputs        [optionCount]textinput.Model
+	stream        *bool
+	defaultStream bool
+	variables     map[string]string
+	focus         int
+	err           error
+}
+
+// newOptionsForm edits options for the pattern. defaultStream is what an
+// unset Stream means, i.e. STREAM_RESULTS.
+func newOptionsForm(options FabricOptions, pattern Pattern, defaultStream bool) optionsForm {
+	f := optionsForm{defaultStream: defaultStream}
+	defaults := FabricOptions{}.withDefaults(pattern)
+	values := [optionCount]string{options.Model, options.Temperature, options.TopP, "", options.Session, options.Context, options.Language}
+	placeholders := [optionCount]string{"fabric default", "0.7", "0.9", "", "none", "none", "en"}
+	for i, value := range [optionCount]string{defaults.Model, defaults.Temperature, defaults.TopP} {
+		if value != "" {
+			placeholders[i] = value
+		}
+	}
+	for i := range f.inputs {
+		f.inputs[i] = textinput.New()
+		f.inputs[i].Prompt = ""
+		f.inputs[i].Placeholder = placeholders[i]
+		f.inputs[i].SetValue(values[i])
+	}
+	f.stream = options.Stream
+	f.variables = options.Variables
+	f.inputs[optionModel].Focus()
+	return f
+}
+
+func (f optionsForm) options() FabricOptions {
+	value := func(i int) string { return strings.TrimSpace(f.inputs[i].Value()) }
+	return FabricOptions{
+		Model:       value(optionModel),
+		Temperature: value(optionTemperature),
+		TopP:        value(optionTopP),
+		Stream:      f.stream,
+		Session:     value(optionSession),
+		Context:     value(optionContext),
+		Language:    value(optionLanguage),
+		Variables:   f.variables,
+	}
+}
+
+func (f optionsForm) update(msg tea.KeyMsg) (optionsForm, tea.Cmd) {
+	switch msg.String() {
+	case "tab", "down":
+		f.setFocus((f.focus + 1) % optionCount)
+		return f, nil
+	case "shift+tab", "up":
+		f.setFocus((f.focus + optionCount - 1) % optionCount)
+		return f, nil
+	case " ":
+		if f.focus == optionStream {
+			f.stream = nextStream(f.stream)
+			return f, nil
+		}
+	}
+	if f.focus == optionStream {
+		return f, nil
+	}
+
+	var cmd tea.Cmd
+	f.inputs[f.focus], cmd = f.inputs[f.focus].Update(msg)
+	return f, cmd
+}
+
+// nextStream cycles the stream option from the default to on to off.
+func nextStream(stream *bool) *bool {
+	switch {
+	case stream == nil:
+		on := true
+		return &on
+	case *stream:
+		off := false
+		return &off
+	}
+	return nil
+}
+
+func (f *optionsForm) setFocus(i int) {
+	f.inputs[f.focus].Blur()
+	f.focus = i
+	f.inputs[f.focus].Focus()
+}
+
+func (f optionsForm) view() string {
+	lines := make([]string, optionCount)
+	for i := range f.inputs {
+		value := f.inputs[i].View()
+		if i == optionStream {
+			switch {
+			case f.stream == nil && f.defaultStream:
+				value = "[-] default (on)"
+			case f.stream == nil:
+				value = "[-] default (off)"
+			case *f.stream:
+				value = "[x] on"
+			default:
+				value = "[ ] off"
+			}
+		}
+		lines[i] = formLabel(optionLabels[i], 12, i == f.focus) + " " + value
+	}
+	if f.err != nil {
+		lines = append(lines, errorStyle.Render(f.err.Error()))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+// formLabel renders a form field label padded to width, highlighted with a
+// marker when the field has focus.
+func formLabel(label string, width int, focused bool) string {
+	if focused {
+		return statusStyle.Render(fmt.Sprintf("› %-*s", width, label))
+	}
+	return fmt.Sprintf("  %-*s", width, label)
+}
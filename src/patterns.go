@@ -2,8 +2,10 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"sort"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/list"
 )
@@ -40,3 +42,37 @@ func sortPatterns(patterns []list.Item, alphaSort, sortByDirName bool) {
 		return false
 	})
 }
+
+// viewPatternInfo shows everything the metadata says about a pattern.
+func (m *model) viewPatternInfo(p Pattern) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", p.FriendlyName)
+	fmt.Fprintf(&b, "%s\n\n", p.ShortDesc)
+	if p.LongDesc != "" {
+		fmt.Fprintf(&b, "## Description\n\n%s\n\n", p.LongDesc)
+	}
+	if p.UsageExample != "" {
+		fmt.Fprintf(&b, "## Usage\n\n%s\n\n", p.UsageExample)
+	}
+	if len(p.RelatedPatterns) > 0 {
+		b.WriteString("## Related patterns\n\n")
+		for _, dirName := range p.RelatedPatterns {
+			if related, ok := m.findPattern(dirName); ok {
+				fmt.Fprintf(&b, "- **%s** (`%s`): %s\n", related.FriendlyName, dirName, related.ShortDesc)
+			} else {
+				fmt.Fprintf(&b, "- `%s`\n", dirName)
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Details\n\n")
+	fmt.Fprintf(&b, "- **Directory:** `%s`\n", p.DirName)
+	fmt.Fprintf(&b, "- **Categories:** %s\n", strings.Join(p.Categories, ", "))
+	fmt.Fprintf(&b, "- **Tags:** %s\n", strings.Join(p.Tags, ", "))
+	fmt.Fprintf(&b, "- **Size:** %d characters, about %d tokens\n", p.CharacterCount, p.EstimatedTokenCount)
+	if p.PreferredModel != "" {
+		fmt.Fprintf(&b, "- **Preferred model:** %s\n", p.PreferredModel)
+	}
+	m.openViewer(p.Title(), b.String())
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// patternPredicate reports whether a pattern matches a query.
+type patternPredicate func(Pattern) bool
+
+// Query token kinds.
+const (
+	tokenWord = iota
+	tokenField
+	tokenNot
+	tokenAnd
+	tokenOr
+	tokenOpen
+	tokenClose
+)
+
+type queryToken struct {
+	kind  int
+	field string
+	value string
+}
+
+// lexQuery splits a query into tokens. Values can be quoted to include
+// spaces, as in cat:"Code and Development".
+func lexQuery(query string) ([]queryToken, error) {
+	var tokens []queryToken
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t':
+			i++
+			continue
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: tokenOpen})
+			i++
+			continue
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: tokenClose})
+			i++
+			continue
+		case r == '-' && i+1 < len(runes) && runes[i+1] != ' ':
+			tokens = append(tokens, queryToken{kind: tokenNot})
+			i++
+			continue
+		}
+
+		// A word, possibly quoted, or a field:value pair.
+		var word strings.Builder
+		field := ""
+		for i < len(runes) && runes[i] != ' ' && runes[i] != '\t' && runes[i] != '(' && runes[i] != ')' {
+			switch {
+			case runes[i] == '"':
+				end := i + 1
+				for end < len(runes) && runes[end] != '"' {
+					end++
+				}
+				if end == len(runes) {
+					return nil, fmt.Errorf("missing closing quote")
+				}
+				word.WriteString(string(runes[i+1 : end]))
+				i = end + 1
+			case runes[i] == ':' && field == "" && word.Len() > 0:
+				field = strings.ToLower(word.String())
+				word.Reset()
+				i++
+			default:
+				word.WriteRune(runes[i])
+				i++
+			}
+		}
+
+		value := word.String()
+		switch {
+		case field != "":
+			tokens = append(tokens, queryToken{kind: tokenField, field: field, value: value})
+		case value == "AND" || value == "&&":
+			tokens = append(tokens, queryToken{kind: tokenAnd})
+		case value == "OR" || value == "||":
+			tokens = append(tokens, queryToken{kind: tokenOr})
+		case value == "NOT":
+			tokens = append(tokens, queryToken{kind: tokenNot})
+		default:
+			tokens = append(tokens, queryToken{kind: tokenWord, value: value})
+		}
+	}
+	return tokens, nil
+}
+
+// isStructuredQuery reports whether the query uses the query language
+// rather than being plain words for fuzzy search.
+func isStructuredQuery(query string) bool {
+	tokens, err := lexQuery(query)
+	if err != nil {
+		return true
+	}
+	for _, token := range tokens {
+		if token.kind != tokenWord {
+			return true
+		}
+	}
+	return false
+}
+
+// parseQuery parses a query such as
+//
+//	tag:security cat:"Code and Development" -tag:ai (name:extract OR name:analyze) tokens:<500
+//
+// into a predicate. Terms next to each other must all match; OR, AND, NOT
+// (or a leading -) and parentheses combine them. AND binds tighter than OR.
+func parseQuery(query string) (patternPredicate, error) {
+	tokens, err := lexQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	predicate, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected )")
+	}
+	if predicate == nil {
+		return func(Pattern) bool { return true }, nil
+	}
+	return predicate, nil
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() (queryToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return queryToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *queryParser) or() (patternPredicate, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		token, ok := p.peek()
+		if !ok || token.kind != tokenOr {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		if left == nil || right == nil {
+			return nil, fmt.Errorf("OR needs a term on both sides")
+		}
+		l, r := left, right
+		left = func(pattern Pattern) bool { return l(pattern) || r(pattern) }
+	}
+}
+
+func (p *queryParser) and() (patternPredicate, error) {
+	var terms []patternPredicate
+	for {
+		token, ok := p.peek()
+		if !ok || token.kind == tokenOr || token.kind == tokenClose {
+			break
+		}
+		if token.kind == tokenAnd {
+			if len(terms) == 0 {
+				return nil, fmt.Errorf("AND needs a term on both sides")
+			}
+			p.pos++
+			continue
+		}
+		term, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	return func(pattern Pattern) bool {
+		for _, term := range terms {
+			if !term(pattern) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func (p *queryParser) unary() (patternPredicate, error) {
+	token, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("the query ends too early")
+	}
+	p.pos++
+	switch token.kind {
+	case tokenNot:
+		term, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(pattern Pattern) bool { return !term(pattern) }, nil
+	case tokenOpen:
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if next, ok := p.peek(); !ok || next.kind != tokenClose {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		if inner == nil {
+			return nil, fmt.Errorf("empty parentheses")
+		}
+		return inner, nil
+	case tokenField:
+		return fieldPredicate(token.field, token.value)
+	case tokenWord:
+		word := token.value
+		return func(pattern Pattern) bool {
+			return containsText(pattern.FriendlyName, word) ||
+				containsText(pattern.DirName, word) ||
+				containsText(pattern.ShortDesc, word) ||
+				containsText(pattern.LongDesc, word) ||
+				containsInSlice(pattern.Tags, word) ||
+				containsInSlice(pattern.Categories, word)
+		}, nil
+	}
+	return nil, fmt.Errorf("unexpected %s", describeToken(token))
+}
+
+func describeToken(token queryToken) string {
+	switch token.kind {
+	case tokenAnd:
+		return "AND"
+	case tokenOr:
+		return "OR"
+	case tokenClose:
+		return ")"
+	}
+	return token.value
+}
+
+// containsText reports whether s contains sub, ignoring case.
+func containsText(s, sub string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(sub))
+}
+
+// fieldPredicate matches one qualified term. tag: and cat: match whole
+// values, like the facet filter; the text fields match substrings.
+func fieldPredicate(field, value string) (patternPredicate, error) {
+	if value == "" {
+		return nil, fmt.Errorf("%s: needs a value", field)
+	}
+	switch field {
+	case "tag", "tags":
+		return func(pattern Pattern) bool { return containsFold(pattern.Tags, value) }, nil
+	case "cat", "category", "categories":
+		return func(pattern Pattern) bool { return containsFold(pattern.Categories, value) }, nil
+	case "name":
+		return func(pattern Pattern) bool { return containsText(pattern.FriendlyName, value) }, nil
+	case "dir", "dir_name":
+		return func(pattern Pattern) bool { return containsText(pattern.DirName, value) }, nil
+	case "desc", "description":
+		return func(pattern Pattern) bool {
+			return containsText(pattern.ShortDesc, value) || containsText(pattern.LongDesc, value)
+		}, nil
+	case "usage":
+		return func(pattern Pattern) bool { return containsText(pattern.UsageExample, value) }, nil
+	case "related":
+		return func(pattern Pattern) bool { return containsInSlice(pattern.RelatedPatterns, value) }, nil
+	case "chars":
+		compare, err := parseComparison(value)
+		if err != nil {
+			return nil, fmt.Errorf("chars:%s: %w", value, err)
+		}
+		return func(pattern Pattern) bool { return compare(pattern.CharacterCount) }, nil
+	case "tokens":
+		compare, err := parseComparison(value)
+		if err != nil {
+			return nil, fmt.Errorf("tokens:%s: %w", value, err)
+		}
+		return func(pattern Pattern) bool { return compare(pattern.EstimatedTokenCount) }, nil
+	}
+	return nil, fmt.Errorf("unknown field %q (use tag, cat, name, dir, desc, usage, related, chars or tokens)", field)
+}
+
+// parseComparison parses <n, <=n, >n, >=n, =n, n or n..m.
+func parseComparison(value string) (func(int) bool, error) {
+	if low, high, ok := strings.Cut(value, ".."); ok {
+		l, errLow := strconv.Atoi(low)
+		h, errHigh := strconv.Atoi(high)
+		if errLow != nil || errHigh != nil {
+			return nil, fmt.Errorf("expected a range like 100..500")
+		}
+		return func(n int) bool { return n >= l && n <= h }, nil
+	}
+
+	for _, op := range []string{"<=", ">=", "<", ">", "="} {
+		if !strings.HasPrefix(value, op) && !(op == "=" && value != "" && value[0] >= '0' && value[0] <= '9') {
+			continue
+		}
+		limit, err := strconv.Atoi(strings.TrimPrefix(value, op))
+		if err != nil {
+			return nil, fmt.Errorf("expected a number")
+		}
+		switch op {
+		case "<=":
+			return func(n int) bool { return n <= limit }, nil
+		case ">=":
+			return func(n int) bool { return n >= limit }, nil
+		case "<":
+			return func(n int) bool { return n < limit }, nil
+		case ">":
+			return func(n int) bool { return n > limit }, nil
+		}
+		return func(n int) bool { return n == limit }, nil
+	}
+	return nil, fmt.Errorf("expected a comparison like <500")
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLexQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    []queryToken
+		wantErr bool
+	}{
+		{query: "", want: nil},
+		{query: "summary", want: []queryToken{{kind: tokenWord, value: "summary"}}},
+		{query: "Tag:security", want: []queryToken{{kind: tokenField, field: "tag", value: "security"}}},
+		{
+			query: `cat:"Code and Development"`,
+			want:  []queryToken{{kind: tokenField, field: "cat", value: "Code and Development"}},
+		},
+		{
+			query: "-tag:ai NOT name:x",
+			want: []queryToken{
+				{kind: tokenNot},
+				{kind: tokenField, field: "tag", value: "ai"},
+				{kind: tokenNot},
+				{kind: tokenField, field: "name", value: "x"},
+			},
+		},
+		{
+			query: "(a OR b) AND c || d && e",
+			want: []queryToken{
+				{kind: tokenOpen},
+				{kind: tokenWord, value: "a"},
+				{kind: tokenOr},
+				{kind: tokenWord, value: "b"},
+				{kind: tokenClose},
+				{kind: tokenAnd},
+				{kind: tokenWord, value: "c"},
+				{kind: tokenOr},
+				{kind: tokenWord, value: "d"},
+				{kind: tokenAnd},
+				{kind: tokenWord, value: "e"},
+			},
+		},
+		{query: "tokens:<500", want: []queryToken{{kind: tokenField, field: "tokens", value: "<500"}}},
+		{query: "a - b", want: []queryToken{{kind: tokenWord, value: "a"}, {kind: tokenWord, value: "-"}, {kind: tokenWord, value: "b"}}},
+		{query: `cat:"Code`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := lexQuery(tt.query)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("lexQuery(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lexQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	patterns := []Pattern{
+		{
+			DirName: "analyze_email_headers", FriendlyName: "Analyze Email Headers",
+			ShortDesc: "Checks SPF and DKIM", Tags: []string{"email headers", "SPF"},
+			Categories: []string{"Security and Threat Analysis"}, EstimatedTokenCount: 300,
+		},
+		{
+			DirName: "explain_code", FriendlyName: "Explain Code",
+			ShortDesc: "Explains code", Tags: []string{"code examples"},
+			Categories: []string{"Code and Development"}, EstimatedTokenCount: 800,
+		},
+		{
+			DirName: "extract_wisdom", FriendlyName: "Extract Wisdom",
+			ShortDesc: "Extracts ideas", Tags: []string{"ai"},
+			Categories: []string{"Data Extraction and Insights"}, EstimatedTokenCount: 1200,
+		},
+	}
+
+	tests := []struct {
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{query: "", want: "analyze_email_headers explain_code extract_wisdom"},
+		{query: "code", want: "explain_code"},
+		{query: "tag:spf", want: "analyze_email_headers"},
+		// tag: and cat: match whole values only.
+		{query: "tag:email", want: ""},
+		{query: `cat:"code and development"`, want: "explain_code"},
+		{query: "cat:code", want: ""},
+		// Bare words still match parts of tags and categories.
+		{query: "headers", want: "analyze_email_headers"},
+		{query: "-tag:ai", want: "analyze_email_headers explain_code"},
+		{query: "name:extract OR name:explain", want: "explain_code extract_wisdom"},
+		{query: "ex tokens:<1000", want: "explain_code"},
+		{query: "tokens:300..1000", want: "analyze_email_headers explain_code"},
+		{query: "(tag:ai OR tag:spf) AND NOT dir:analyze", want: "extract_wisdom"},
+		{query: "extract AND", want: "extract_wisdom"},
+		{query: "OR code", wantErr: true},
+		{query: "(code", wantErr: true},
+		{query: "code)", wantErr: true},
+		{query: "()", wantErr: true},
+		{query: "colour:red", wantErr: true},
+		{query: "tokens:lots", wantErr: true},
+	}
+	for _, tt := range tests {
+		predicate, err := parseQuery(tt.query)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseQuery(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		var matched []string
+		for _, pattern := range patterns {
+			if predicate(pattern) {
+				matched = append(matched, pattern.DirName)
+			}
+		}
+		if got := strings.Join(matched, " "); got != tt.want {
+			t.Errorf("parseQuery(%q) matches %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/sahilm/fuzzy"
+)
+
+// Fields a search can match, best first: a match in the name beats one in
+// the directory name, which beats tags and categories, which beat the
+// description, however strong. The fuzzy score only orders matches in the
+// same field.
+const (
+	fieldName = iota
+	fieldDirName
+	fieldTag
+	fieldDescription
+)
+
+// contiguousBonus is added when the query appears as is in a field, so that
+// "sum" ranks "summarize" above "spiritual ... comparison".
+const contiguousBonus = 100
+
+// patternMatch holds the positions of the matched runes in a pattern's
+// list title and description, for highlighting.
+type patternMatch struct {
+	title []int
+	desc  []int
+}
+
+// patternHighlights is shared with the list delegate and holds the matches
+// of the current search, keyed by dir_name.
+type patternHighlights struct {
+	byDir map[string]patternMatch
+}
+
+func (h *patternHighlights) set(matches map[string]patternMatch) {
+	h.byDir = matches
+}
+
+func (h *patternHighlights) get(dirName string) (patternMatch, bool) {
+	match, ok := h.byDir[dirName]
+	return match, ok
+}
+
+// rankPatterns fuzzy matches the query against the patterns and returns the
+// matching ones, best first, along with what to highlight.
+func rankPatterns(patterns []list.Item, query string) ([]list.Item, map[string]patternMatch) {
+	type ranked struct {
+		item  list.Item
+		field int
+		score int
+	}
+	var results []ranked
+	matches := map[string]patternMatch{}
+
+	for _, item := range patterns {
+		pattern := item.(Pattern)
+		best, found := ranked{item: item}, false
+		var match patternMatch
+		consider := func(field, score int) {
+			if !found || field < best.field || field == best.field && score > best.score {
+				best.field, best.score, found = field, score, true
+			}
+		}
+
+		if m, ok := fuzzyMatch(query, pattern.FriendlyName); ok {
+			consider(fieldName, m.Score)
+			match.title = runeIndexes(pattern.FriendlyName, m.MatchedIndexes, 0)
+		}
+		if m, ok := fuzzyMatch(query, pattern.DirName); ok {
+			consider(fieldDirName, m.Score)
+			if match.title == nil {
+				// The directory name follows the friendly name in the title.
+				offset := utf8.RuneCountInString(pattern.Title()) - utf8.RuneCountInString(pattern.DirName) - 2
+				match.title = runeIndexes(pattern.DirName, m.MatchedIndexes, offset)
+			}
+		}
+		for _, value := range append(append([]string{}, pattern.Tags...), pattern.Categories...) {
+			if m, ok := fuzzyMatch(query, value); ok {
+				consider(fieldTag, m.Score)
+			}
+		}
+		if m, ok := fuzzyMatch(query, pattern.ShortDesc); ok {
+			consider(fieldDescription, m.Score)
+			match.desc = runeIndexes(pattern.ShortDesc, m.MatchedIndexes, 0)
+		}
+		// Almost any query is a subsequence of the long description, so it
+		// only counts when it appears as is. It is not shown, so there is
+		// nothing to highlight.
+		if containsText(pattern.LongDesc, query) {
+			consider(fieldDescription, contiguousBonus)
+		}
+
+		if found {
+			results = append(results, best)
+			matches[pattern.DirName] = match
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].field != results[j].field {
+			return results[i].field < results[j].field
+		}
+		return results[i].score > results[j].score
+	})
+	items := make([]list.Item, len(results))
+	for i, r := range results {
+		items[i] = r.item
+	}
+	return items, matches
+}
+
+// fuzzyMatch matches the query against a single string.
+func fuzzyMatch(query, s string) (fuzzy.Match, bool) {
+	found := fuzzy.Find(query, []string{s})
+	if len(found) == 0 {
+		return fuzzy.Match{}, false
+	}
+	match := found[0]
+	lower := strings.ToLower(s)
+	if at := strings.Index(lower, strings.ToLower(query)); at >= 0 {
+		match.Score += contiguousBonus
+		if len(lower) == len(s) {
+			// Highlight the occurrence rather than scattered characters.
+			match.MatchedIndexes = match.MatchedIndexes[:0]
+			for i := range query {
+				match.MatchedIndexes = append(match.MatchedIndexes, at+i)
+			}
+		}
+	}
+	return match, true
+}
+
+// runeIndexes converts the byte offsets of matched characters in s to rune
+// positions, shifted by offset.
+func runeIndexes(s string, byteIndexes []int, offset int) []int {
+	positions := make([]int, len(byteIndexes))
+	for i, b := range byteIndexes {
+		positions[i] = utf8.RuneCountInString(s[:b]) + offset
+	}
+	return positions
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+func TestRankPatternsByField(t *testing.T) {
+	patterns := []list.Item{
+		Pattern{
+			DirName: "write_essay", FriendlyName: "Write Essay",
+			ShortDesc: "Writes a short essay about a topic", Tags: []string{"writing"},
+		},
+		Pattern{
+			DirName: "analyze_paper", FriendlyName: "Analyze Paper",
+			ShortDesc: "Summarizes an academic paper", Tags: []string{"research"},
+		},
+		Pattern{
+			DirName: "essay_outline", FriendlyName: "Outline a Long Piece of Writing",
+			ShortDesc: "Plans the sections of a document", Tags: []string{"essay"},
+		},
+		Pattern{
+			// The name only matches "essay" as scattered letters across a
+			// long title, which scores far below the description's exact
+			// occurrence.
+			DirName: "extract_sponsors", FriendlyName: "Extract " + strings.Repeat("Sponsors and ", 20) + "Yearly Trends",
+			ShortDesc: "Lists sponsors",
+		},
+		Pattern{
+			DirName: "rate_content", FriendlyName: "Rate Content",
+			ShortDesc: "Rates an essay", Tags: []string{"rating"},
+		},
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		// Name matches first, the closest one leading; then the directory
+		// name, then the description.
+		{query: "essay", want: "write_essay extract_sponsors essay_outline rate_content"},
+		{query: "research", want: "analyze_paper"},
+		{query: "zzz", want: ""},
+	}
+	for _, tt := range tests {
+		items, _ := rankPatterns(patterns, tt.query)
+		var got []string
+		for _, item := range items {
+			got = append(got, item.(Pattern).DirName)
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("rankPatterns(%q) = %q, want %q", tt.query, strings.Join(got, " "), tt.want)
+		}
+	}
+}
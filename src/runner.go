@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// executor carries out an invocation, either by running a program or by
+// talking to a model API directly.
+type executor interface {
+	Start(stdin io.Reader, stdout, stderr io.Writer) error
+	Wait() error
+	Kill() error
+}
+
+// processExecutor runs a program in its own process group.
+type processExecutor struct {
+	cmd *exec.Cmd
+}
+
+func newProcessExecutor(program string, args []string) *processExecutor {
+	cmd := exec.Command(program, args...)
+	setProcessGroup(cmd)
+	return &processExecutor{cmd: cmd}
+}
+
+func (e *processExecutor) Start(stdin io.Reader, stdout, stderr io.Writer) error {
+	e.cmd.Stdin = stdin
+	e.cmd.Stdout = stdout
+	e.cmd.Stderr = stderr
+	return e.cmd.Start()
+}
+
+func (e *processExecutor) Wait() error { return e.cmd.Wait() }
+func (e *processExecutor) Kill() error { return killProcessGroup(e.cmd.Process) }
+
+// run tracks an invocation started from the TUI.
+type run struct {
+	invocation Invocation
+	exec       executor
+	events     chan tea.Msg
+	started    time.Time
+	finished   time.Time
+	done       bool
+	err        error
+	output     strings.Builder
+	input      []byte
+
+	// mu guards the fields below, which are shared with the goroutine
+	// waiting for the process.
+	mu     sync.Mutex
+	exited bool
+	reason error
+}
+
+// Reasons a run was interrupted before the process exited on its own.
+var (
+	errRunCancelled = errors.New("cancelled")
+	errRunTimedOut  = errors.New("timed out")
+)
+
+// runOutputMsg carries a chunk of stdout or stderr from a running command.
+type runOutputMsg struct {
+	run   *run
+	chunk string
+}
+
+// runFinishedMsg is sent once the command has exited.
+type runFinishedMsg struct {
+	run *run
+	err error
+}
+
+// runTickMsg refreshes the elapsed time while a command is running.
+type runTickMsg struct {
+	run *run
+}
+
+// chanWriter forwards everything written to it as runOutputMsg events.
+type chanWriter struct {
+	run *run
+}
+
+func (w chanWriter) Write(p []byte) (int, error) {
+	w.run.events <- runOutputMsg{run: w.run, chunk: string(p)}
+	return len(p), nil
+}
+
+// startRun reads the input of the invocation, starts it and returns the run
+// together with the commands that feed its events into the program.
+func startRun(inv Invocation) (*run, tea.Cmd) {
+	r := &run{
+		invocation: inv,
+		exec:       inv.executor(),
+		events:     make(chan tea.Msg),
+		started:    time.Now(),
+	}
+
+	fail := func(err error) (*run, tea.Cmd) {
+		r.exited = true
+		r.done = true
+		r.finished = time.Now()
+		r.err = err
+		return r, nil
+	}
+
+	var stdin io.Reader
+	var input []byte
+	if inv.Input != nil {
+		var err error
+		if input, err = inv.Input.Read(); err != nil {
+			return fail(fmt.Errorf("reading input: %w", err))
+		}
+		stdin = bytes.NewReader(input)
+	}
+	inv.Sinks = resolveSinks(inv.Sinks, input)
+	r.input = input
+
+	stdout, closeSinks, err := inv.openSinks(chanWriter{run: r})
+	r.invocation = inv
+	if err != nil {
+		return fail(err)
+	}
+
+	if err := r.exec.Start(stdin, stdout, chanWriter{run: r}); err != nil {
+		closeSinks()
+		return fail(err)
+	}
+
+	var timer *time.Timer
+	if inv.Timeout > 0 {
+		timer = time.AfterFunc(inv.Timeout, func() { r.interrupt(errRunTimedOut) })
+	}
+
+	go func() {
+		err := r.exec.Wait()
+		if timer != nil {
+			timer.Stop()
+		}
+
+		r.mu.Lock()
+		r.exited = true
+		reason := r.reason
+		r.mu.Unlock()
+		if reason != nil {
+			// Keep the partial output, but mark it as incomplete.
+			fmt.Fprintf(stdout, "\n\n---\n_FabricForge: run %s after %s, output is incomplete._\n", reason, time.Since(r.started).Round(100*time.Millisecond))
+			err = reason
+		}
+
+		if closeErr := closeSinks(); err == nil {
+			err = closeErr
+		}
+		if inv.FrontMatter {
+			if fmErr := r.writeFrontMatter(input, err); err == nil {
+				err = fmErr
+			}
+		}
+		r.events <- runFinishedMsg{run: r, err: err}
+		close(r.events)
+	}()
+
+	return r, tea.Batch(r.listen(), r.tick())
+}
+
+// writeFrontMatter adds provenance front matter to the output files of the
+// run.
+func (r *run) writeFrontMatter(input []byte, runErr error) error {
+	frontMatter := provenance{
+		Pattern:    r.invocation.Pattern,
+		Model:      r.invocation.Model,
+		Started:    r.started,
+		Duration:   time.Since(r.started),
+		Err:        runErr,
+		InputHash:  inputHash(input),
+		InputBytes: len(input),
+	}.frontMatter()
+
+	for _, sink := range r.invocation.Sinks {
+		if err := prependFrontMatter(sink.Path, frontMatter); err != nil {
+			return fmt.Errorf("writing front matter: %w", err)
+		}
+	}
+	return nil
+}
+
+// listen waits for the next event of the run.
+func (r *run) listen() tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-r.events
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+func (r *run) tick() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return runTickMsg{run: r}
+	})
+}
+
+// cancel stops the run if it is still going.
+func (r *run) cancel() {
+	r.interrupt(errRunCancelled)
+}
+
+// interrupt kills a running invocation and records why.
+func (r *run) interrupt(reason error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.exited || r.reason != nil {
+		return
+	}
+	r.reason = reason
+	r.exec.Kill()
+}
+
+func (r *run) elapsed() time.Duration {
+	if r.done {
+		return r.finished.Sub(r.started)
+	}
+	return time.Since(r.started)
+}
+
+// status describes the state of the run for the status line.
+func (r *run) status() string {
+	elapsed := r.elapsed().Round(100 * time.Millisecond)
+	if !r.done {
+		return fmt.Sprintf("Running… %s", elapsed.Round(time.Second))
+	}
+	switch {
+	case errors.Is(r.err, errRunCancelled):
+		return fmt.Sprintf("Cancelled after %s (partial output kept)", elapsed)
+	case errors.Is(r.err, errRunTimedOut):
+		return fmt.Sprintf("Timed out after %s (partial output kept)", elapsed)
+	case r.err != nil:
+		return fmt.Sprintf("Failed after %s: %v", elapsed, r.err)
+	}
+	return fmt.Sprintf("Finished in %s (exit status 0)", elapsed)
+}
@@ -0,0 +1,507 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// searchIndexFile is the name of the index file kept in the output
+// directory.
+const searchIndexFile = ".fabricforge_index.json"
+
+// indexedOutput is a saved output in the search index.
+type indexedOutput struct {
+	ModTime time.Time      `json:"mod_time"`
+	Size    int64          `json:"size"`
+	Pattern string         `json:"pattern,omitempty"`
+	Date    time.Time      `json:"date"`
+	Terms   map[string]int `json:"terms"`
+	Length  int            `json:"length"`
+}
+
+// searchIndex is a full-text index over the saved outputs in the output
+// directory, keyed by their path relative to it. It is kept up to date
+// incrementally: only files that changed since they were indexed are read
+// again.
+type searchIndex struct {
+	dir   string
+	match outputMatcher
+	Docs  map[string]*indexedOutput `json:"docs"`
+}
+
+// loadSearchIndex loads the index of dir, starting an empty one if there is
+// none or it cannot be read.
+func loadSearchIndex(dir string, match outputMatcher) *searchIndex {
+	index := &searchIndex{dir: dir, match: match}
+	if err := loadJSON(filepath.Join(dir, searchIndexFile), index); err != nil || index.Docs == nil {
+		index.Docs = map[string]*indexedOutput{}
+	}
+	return index
+}
+
+func (x *searchIndex) save() error {
+	if err := os.MkdirAll(x.dir, os.ModePerm); err != nil {
+		return err
+	}
+	return saveJSON(filepath.Join(x.dir, searchIndexFile), x)
+}
+
+// update indexes new and changed files, drops deleted ones and saves the
+// index if anything changed. patterns are the known pattern names, used to
+// tell which pattern produced files without front matter. Files that cannot
+// be read are left out and the first such error is returned.
+func (x *searchIndex) update(patterns []string) error {
+	files, err := listOutputs(x.dir, x.match)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	var indexErr error
+	seen := make(map[string]bool, len(files))
+	for _, file := range files {
+		seen[file.rel] = true
+		if doc, ok := x.Docs[file.rel]; ok && doc.ModTime.Equal(file.modified) && doc.Size == file.size {
+			continue
+		}
+		if err := x.index(file, patterns); err != nil {
+			if indexErr == nil {
+				indexErr = err
+			}
+			continue
+		}
+		changed = true
+	}
+	for rel := range x.Docs {
+		if !seen[rel] {
+			delete(x.Docs, rel)
+			changed = true
+		}
+	}
+
+	if changed {
+		if err := x.save(); err != nil {
+			return err
+		}
+	}
+	return indexErr
+}
+
+// add indexes the files at paths right away, as they are written. Files
+// outside the output directory are not indexed.
+func (x *searchIndex) add(paths []string, patterns []string) error {
+	changed := false
+	for _, path := range paths {
+		rel, err := filepath.Rel(x.dir, path)
+		if err != nil || strings.HasPrefix(rel, "..") || !x.match.matches(path) {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := x.index(outputFile{path: path, rel: rel, modified: info.ModTime(), size: info.Size()}, patterns); err != nil {
+			return err
+		}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return x.save()
+}
+
+func (x *searchIndex) index(file outputFile, patterns []string) error {
+	content, err := os.ReadFile(file.path)
+	if err != nil {
+		return err
+	}
+
+	doc := &indexedOutput{ModTime: file.modified, Size: file.size, Date: file.modified, Terms: map[string]int{}}
+	fields := frontMatterFields(content)
+	doc.Pattern = fields["pattern"]
+	if doc.Pattern == "" {
+		doc.Pattern = guessPattern(file.rel, patterns)
+	}
+	if started, err := time.Parse(time.RFC3339, fields["timestamp"]); err == nil {
+		doc.Date = started
+	}
+	for _, term := range searchTerms(string(stripFrontMatter(content))) {
+		doc.Terms[term]++
+		doc.Length++
+	}
+	x.Docs[file.rel] = doc
+	return nil
+}
+
+// guessPattern finds the pattern that wrote a file from its name, as
+// produced by the default naming template, chains and fan-outs.
+func guessPattern(rel string, patterns []string) string {
+	name := strings.TrimSuffix(filepath.Base(rel), filepath.Ext(rel))
+	if len(name) > 3 && name[0] >= '0' && name[0] <= '9' && name[1] >= '0' && name[1] <= '9' && name[2] == '_' {
+		name = name[3:]
+	}
+	best := ""
+	for _, pattern := range patterns {
+		if (name == pattern || strings.HasPrefix(name, pattern+"_")) && len(pattern) > len(best) {
+			best = pattern
+		}
+	}
+	return best
+}
+
+// searchTerms splits text into lower case words.
+func searchTerms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := words[:0]
+	for _, word := range words {
+		if len([]rune(word)) > 1 {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// outputQuery is a parsed search: words that must all appear, plus
+// filters written as pattern:<name>, cat:<category>, after:<date> and
+// before:<date> (dates as YYYY-MM-DD).
+type outputQuery struct {
+	terms    []string
+	pattern  string
+	category string
+	after    time.Time
+	before   time.Time
+}
+
+func parseOutputQuery(text string) (outputQuery, error) {
+	var q outputQuery
+	var words []string
+	for _, field := range strings.Fields(text) {
+		key, value, ok := strings.Cut(field, ":")
+		if !ok || value == "" {
+			words = append(words, field)
+			continue
+		}
+		key = strings.ToLower(key)
+		switch key {
+		case "pattern", "p":
+			q.pattern = value
+		case "cat", "category":
+			q.category = value
+		case "after", "before":
+			date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+			if err != nil {
+				return q, fmt.Errorf("%s: dates are written as YYYY-MM-DD", field)
+			}
+			if key == "after" {
+				q.after = date
+			} else {
+				q.before = date.AddDate(0, 0, 1)
+			}
+		default:
+			words = append(words, field)
+		}
+	}
+	q.terms = searchTerms(strings.Join(words, " "))
+	return q, nil
+}
+
+// searchHit is a matching output in the search results. Its snippet is
+// read when the hit is first shown.
+type searchHit struct {
+	outputFile
+	pattern  string
+	date     time.Time
+	score    float64
+	terms    []string
+	snippets *snippetCache
+}
+
+func (h searchHit) Title() string {
+	if h.pattern == "" {
+		return h.rel
+	}
+	return fmt.Sprintf("%s (%s)", h.rel, h.pattern)
+}
+
+func (h searchHit) Description() string {
+	date := h.date.Local().Format("2006-01-02 15:04")
+	var line string
+	if h.snippets != nil {
+		line = h.snippets.get(h.outputFile, h.terms)
+	}
+	if line == "" {
+		return date
+	}
+	return date + "  " + line
+}
+
+// snippetWidth is the length snippets are trimmed to.
+const snippetWidth = 60
+
+// snippetCacheLimit is the number of snippets kept before the cache starts
+// over.
+const snippetCacheLimit = 1000
+
+// snippetCache keeps the snippets of the search hits that were shown, so
+// that a file is read once per query rather than on every keystroke and
+// redraw.
+type snippetCache struct {
+	snippets map[snippetKey]string
+}
+
+type snippetKey struct {
+	path     string
+	modified time.Time
+	terms    string
+}
+
+func (c *snippetCache) get(file outputFile, terms []string) string {
+	key := snippetKey{path: file.path, modified: file.modified, terms: strings.Join(terms, " ")}
+	if s, ok := c.snippets[key]; ok {
+		return s
+	}
+	if c.snippets == nil || len(c.snippets) >= snippetCacheLimit {
+		c.snippets = map[snippetKey]string{}
+	}
+	s := snippet(file.path, terms, snippetWidth)
+	c.snippets[key] = s
+	return s
+}
+
+// search returns the outputs that match the query, best first. categories
+// maps pattern names to their categories for the cat: filter. The last word
+// also matches as a prefix, so results show up while it is being typed.
+func (x *searchIndex) search(q outputQuery, categories map[string][]string) []searchHit {
+	type candidate struct {
+		rel    string
+		doc    *indexedOutput
+		counts []int
+	}
+	var candidates []candidate
+	containing := make([]int, len(q.terms))
+	for rel, doc := range x.Docs {
+		if q.pattern != "" && !strings.Contains(strings.ToLower(doc.Pattern), strings.ToLower(q.pattern)) {
+			continue
+		}
+		if q.category != "" && !containsFold(categories[doc.Pattern], q.category) {
+			continue
+		}
+		if !q.after.IsZero() && doc.Date.Before(q.after) {
+			continue
+		}
+		if !q.before.IsZero() && !doc.Date.Before(q.before) {
+			continue
+		}
+
+		counts := make([]int, len(q.terms))
+		for i, term := range q.terms {
+			counts[i] = doc.Terms[term]
+			if counts[i] == 0 && i == len(q.terms)-1 {
+				counts[i] = prefixCount(doc.Terms, term)
+			}
+			if counts[i] > 0 {
+				containing[i]++
+			}
+		}
+		candidates = append(candidates, candidate{rel: rel, doc: doc, counts: counts})
+	}
+
+	var hits []searchHit
+	for _, c := range candidates {
+		// Every word must appear; each adds its TF-IDF weight.
+		score := 0.0
+		for i, count := range c.counts {
+			if count == 0 {
+				score = -1
+				break
+			}
+			idf := math.Log(1 + float64(len(x.Docs))/float64(containing[i]))
+			score += float64(count) / float64(max(c.doc.Length, 1)) * idf
+		}
+		if score < 0 {
+			continue
+		}
+		hits = append(hits, searchHit{
+			outputFile: outputFile{path: filepath.Join(x.dir, c.rel), rel: c.rel, modified: c.doc.ModTime, size: c.doc.Size},
+			pattern:    c.doc.Pattern,
+			date:       c.doc.Date,
+			score:      score,
+		})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return hits[i].date.After(hits[j].date)
+	})
+	return hits
+}
+
+func prefixCount(terms map[string]int, prefix string) int {
+	count := 0
+	for term, n := range terms {
+		if strings.HasPrefix(term, prefix) {
+			count += n
+		}
+	}
+	return count
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// snippet returns the first line of the file that contains one of the
+// terms, trimmed to width.
+func snippet(path string, terms []string, width int) string {
+	content, err := os.ReadFile(path)
+	if err != nil || len(terms) == 0 {
+		return ""
+	}
+	for _, line := range strings.Split(string(stripFrontMatter(content)), "\n") {
+		lower := strings.ToLower(line)
+		for _, term := range terms {
+			if i := strings.Index(lower, term); i >= 0 {
+				line = strings.TrimSpace(line)
+				if runes := []rune(line); len(runes) > width {
+					line = string(runes[:width]) + "…"
+				}
+				return line
+			}
+		}
+	}
+	return ""
+}
+
+// indexOutputs adds the files written by a run to the search index.
+func (m *model) indexOutputs(r *run) {
+	var paths []string
+	for _, sink := range r.invocation.Sinks {
+		paths = append(paths, sink.Path)
+	}
+	if len(paths) == 0 {
+		return
+	}
+	if err := m.searchIndex().add(paths, m.allDirectories); err != nil {
+		m.inputErr = fmt.Errorf("indexing outputs: %w", err)
+	}
+}
+
+// searchIndex returns the index of the output directory, loading it on
+// first use.
+func (m *model) searchIndex() *searchIndex {
+	if m.index == nil {
+		m.index = loadSearchIndex(m.config.OutputDir, m.outputMatcher())
+	}
+	return m.index
+}
+
+// openOutputSearch shows the search over saved outputs.
+func (m *model) openOutputSearch() tea.Cmd {
+	m.inputErr = m.searchIndex().update(m.allDirectories)
+	m.state = "searching_outputs"
+	m.textInput.Placeholder = "words, pattern:<name>, cat:<category>, after:YYYY-MM-DD, before:YYYY-MM-DD"
+	m.textInput.SetValue("")
+	m.refreshOutputSearch()
+	return m.textInput.Focus()
+}
+
+// refreshOutputSearch runs the query in the text input.
+func (m *model) refreshOutputSearch() {
+	q, err := parseOutputQuery(m.textInput.Value())
+	if err != nil {
+		m.inputErr = err
+		return
+	}
+	m.inputErr = nil
+
+	categories := make(map[string][]string, len(m.allPatterns))
+	for _, item := range m.allPatterns {
+		pattern := item.(Pattern)
+		categories[pattern.DirName] = pattern.Categories
+	}
+
+	if m.snippets == nil {
+		m.snippets = &snippetCache{}
+	}
+	hits := m.searchIndex().search(q, categories)
+	items := make([]list.Item, len(hits))
+	for i, hit := range hits {
+		hit.terms = q.terms
+		hit.snippets = m.snippets
+		items[i] = hit
+	}
+	m.list.SetItems(items)
+	m.list.Select(0)
+}
+
+// updateOutputSearch handles key presses in the output search.
+func (m model) updateOutputSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.inputErr = nil
+		m.state = "selecting"
+		m.textInput.Placeholder = m.config.Placeholder
+		m.textInput.SetValue("")
+		m.list.SetItems(m.filteredItems)
+		return m, nil
+	case "up", "down", "pgup", "pgdown":
+		var cmd tea.Cmd
+		m.list, cmd = m.list.Update(msg)
+		return m, cmd
+	case "enter":
+		hit, ok := m.list.SelectedItem().(searchHit)
+		if !ok {
+			return m, nil
+		}
+		if err := m.openOutputFile(hit.path); err != nil {
+			m.inputErr = err
+			return m, nil
+		}
+		// Highlight the first search word in the viewer.
+		if q, err := parseOutputQuery(m.textInput.Value()); err == nil && len(q.terms) > 0 {
+			m.viewer.query = q.terms[0]
+			m.viewer.find()
+			m.viewer.jumpToMatch(0)
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.textInput, cmd = m.textInput.Update(msg)
+	m.refreshOutputSearch()
+	return m, cmd
+}
+
+func (m model) outputSearchView() string {
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		fmt.Sprintf("Search saved outputs in %s (↑/↓ to select, enter to open, esc to go back):", m.config.OutputDir),
+		m.textInput.View(),
+		m.list.View(),
+	)
+	if m.inputErr != nil {
+		content = lipgloss.JoinVertical(lipgloss.Left, content, errorStyle.Render(m.inputErr.Error()))
+	}
+	return content
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxSavedSearchKeys is the number of saved searches that get a digit key.
+const maxSavedSearchKeys = 9
+
+// savedSearch is a filter state that can be applied again. Filter is one of
+// the filter menu options; Query is the search text or the selected tag,
+// category or directory.
+type savedSearch struct {
+	Name     string       `json:"name"`
+	Filter   string       `json:"filter"`
+	Query    string       `json:"query,omitempty"`
+	Facets   []savedFacet `json:"facets,omitempty"`
+	MatchAll bool         `json:"match_all,omitempty"`
+}
+
+type savedFacet struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// describe summarises the filter, e.g. `cat: Security and Threat Analysis
+// AND tag: report`.
+func (s savedSearch) describe() string {
+	switch s.Filter {
+	case "Facets":
+		parts := make([]string, len(s.Facets))
+		for i, f := range s.Facets {
+			parts[i] = fmt.Sprintf("%s: %s", f.Kind, f.Value)
+		}
+		join := " OR "
+		if s.MatchAll {
+			join = " AND "
+		}
+		return strings.Join(parts, join)
+	case "Global Search", "Describe":
+		return fmt.Sprintf("%s %q", s.Filter, s.Query)
+	}
+	return fmt.Sprintf("%s: %s", s.Filter, s.Query)
+}
+
+// savedSearchItem is a saved search listed in the filter menu.
+type savedSearchItem struct {
+	search savedSearch
+	key    int
+}
+
+func (i savedSearchItem) Title() string {
+	if i.key > 0 {
+		return fmt.Sprintf("%d. %s", i.key, i.search.Name)
+	}
+	return i.search.Name
+}
+
+func (i savedSearchItem) Description() string { return i.search.describe() }
+func (i savedSearchItem) FilterValue() string { return i.search.Name }
+
+// searchStore keeps the saved searches in the order they were first saved.
+type searchStore struct {
+	path     string
+	Searches []savedSearch `json:"searches"`
+}
+
+func loadSearchStore() *searchStore {
+	store := &searchStore{}
+	path, err := userConfigPath("searches.json")
+	if err != nil {
+		return store
+	}
+	store.path = path
+	loadJSON(path, store)
+	return store
+}
+
+func (s *searchStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	return saveJSON(s.path, s)
+}
+
+// put saves the search, replacing one with the same name.
+func (s *searchStore) put(search savedSearch) error {
+	for i, existing := range s.Searches {
+		if strings.EqualFold(existing.Name, search.Name) {
+			s.Searches[i] = search
+			return s.save()
+		}
+	}
+	s.Searches = append(s.Searches, search)
+	return s.save()
+}
+
+func (s *searchStore) remove(name string) error {
+	for i, existing := range s.Searches {
+		if existing.Name == name {
+			s.Searches = append(s.Searches[:i], s.Searches[i+1:]...)
+			return s.save()
+		}
+	}
+	return nil
+}
+
+// filterMenuItems returns the filter options followed by the saved
+// searches.
+func (m *model) filterMenuItems() []list.Item {
+	items := append([]list.Item{}, m.filterOptions...)
+	for i, search := range m.searches.Searches {
+		key := i + 1
+		if key > maxSavedSearchKeys {
+			key = 0
+		}
+		items = append(items, savedSearchItem{search: search, key: key})
+	}
+	return items
+}
+
+// savedSearchKey returns the saved search bound to a digit key.
+func (m *model) savedSearchKey(key string) (savedSearch, bool) {
+	if len(key) != 1 || key[0] < '1' || key[0] > '9' {
+		return savedSearch{}, false
+	}
+	n := int(key[0] - '0')
+	if n > len(m.searches.Searches) {
+		return savedSearch{}, false
+	}
+	return m.searches.Searches[n-1], true
+}
+
+// applySavedSearch restores a saved filter state and shows the result.
+func (m *model) applySavedSearch(s savedSearch) {
+	m.inputErr = nil
+	m.highlights.set(nil)
+	m.facets.clear()
+	m.currentFilter = s.Filter
+	m.state = "selecting"
+	switch s.Filter {
+	case "Facets":
+		for _, f := range s.Facets {
+			m.facets.toggle(facetValue{kind: f.Kind, value: f.Value})
+		}
+		m.facets.matchAll = s.MatchAll
+		m.applyFacets()
+	case "Global Search", "Describe":
+		m.textInput.SetValue(s.Query)
+		m.applySearch()
+		m.textInput.SetValue("")
+	default:
+		m.filteredItems = filterPatternsByMetadata(m.allPatterns, s.Filter, s.Query)
+		m.list.SetItems(m.filteredItems)
+		m.currentSearch = savedSearch{Filter: s.Filter, Query: s.Query}
+	}
+	m.list.Select(0)
+	m.resizeList()
+}
+
+// openSaveSearch asks for a name for the current filter state.
+func (m *model) openSaveSearch() tea.Cmd {
+	m.state = "saving_search"
+	m.inputErr = nil
+	m.textInput.Placeholder = "Name for this search"
+	m.textInput.SetValue("")
+	return m.textInput.Focus()
+}
+
+// updateSaveSearch handles key presses while naming a search.
+func (m model) updateSaveSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.inputErr = nil
+		m.state = "selecting"
+		m.textInput.Placeholder = m.config.Placeholder
+		m.textInput.SetValue("")
+		return m, nil
+	case "enter":
+		name := strings.TrimSpace(m.textInput.Value())
+		if name == "" {
+			return m, nil
+		}
+		search := m.currentSearch
+		search.Name = name
+		if err := m.searches.put(search); err != nil {
+			m.inputErr = fmt.Errorf("saving search: %w", err)
+			return m, nil
+		}
+		m.state = "selecting"
+		m.textInput.Placeholder = m.config.Placeholder
+		m.textInput.SetValue("")
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.textInput, cmd = m.textInput.Update(msg)
+	return m, cmd
+}
+
+func (m model) saveSearchView() string {
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		fmt.Sprintf("Save %s as (enter to save, esc to cancel):", m.currentSearch.describe()),
+		m.textInput.View(),
+	)
+	if m.inputErr != nil {
+		content = lipgloss.JoinVertical(lipgloss.Left, errorStyle.Render(m.inputErr.Error()), content)
+	}
+	return content
+}
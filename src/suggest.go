@@ -0,0 +1,247 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// suggestSampleLimit is how much of the input is inspected for suggestions.
+const suggestSampleLimit = 64 << 10
+
+// maxSuggestions is the number of patterns moved to the top of the list.
+const maxSuggestions = 6
+
+// contentRule recognises a kind of input and names the patterns that suit
+// it: some by dir_name, the rest through their tags and categories. Names,
+// tags and categories are spelled as in the pattern metadata, and tags and
+// categories match whole values.
+type contentRule struct {
+	label      string
+	detect     func(lines []string) bool
+	patterns   []string
+	tags       []string
+	categories []string
+}
+
+var (
+	emailHeaderLine = regexp.MustCompile(`(?i)^(received|return-path|authentication-results|dkim-signature|arc-seal|message-id|received-spf):`)
+	diffLine        = regexp.MustCompile(`^(diff --git |index [0-9a-f]+\.\.[0-9a-f]+|@@ -\d+(,\d+)? \+\d+(,\d+)? @@|--- (a/|/dev/null)|\+\+\+ (b/|/dev/null))`)
+	urlLine         = regexp.MustCompile(`^(- |\* |\d+\. )?<?https?://\S+>?$`)
+	speakerLine     = regexp.MustCompile(`^(\[?\(?\d{1,2}:\d{2}(:\d{2})?([.,]\d+)?\)?\]?\s*|[A-Z][\w.' -]{0,30}:\s+\S)`)
+	codeLine        = regexp.MustCompile(`^\s*(func |def |class |import |from \S+ import |package |#include|public |private |const |let |var |return\b|if \(|for \(|while \(|fn |use |} else|#!/)|[;{}]\s*$`)
+)
+
+// contentRules are tried in order; more specific kinds come first.
+var contentRules = []contentRule{
+	{
+		label: "email headers",
+		detect: func(lines []string) bool {
+			return countMatching(lines, emailHeaderLine) >= 2
+		},
+		patterns: []string{"analyze_email_headers"},
+		tags:     []string{"email headers", "DKIM", "SPF"},
+	},
+	{
+		label: "a git diff",
+		detect: func(lines []string) bool {
+			return countMatching(lines, diffLine) >= 2
+		},
+		patterns:   []string{"summarize_git_diff", "create_git_diff_commit", "summarize_git_changes"},
+		tags:       []string{"git", "code changes", "commit messages"},
+		categories: []string{"Version Control"},
+	},
+	{
+		label: "a list of URLs",
+		detect: func(lines []string) bool {
+			return len(lines) > 0 && countMatching(lines, urlLine)*10 >= len(lines)*8
+		},
+		patterns:   []string{"extract_article_wisdom", "get_youtube_rss", "extract_videoid"},
+		categories: []string{"Data Extraction and Insights"},
+	},
+	{
+		label: "a transcript",
+		detect: func(lines []string) bool {
+			if len(lines) > 0 && strings.HasPrefix(lines[0], "WEBVTT") {
+				return true
+			}
+			return len(lines) >= 3 && countMatching(lines, speakerLine)*10 >= len(lines)*4
+		},
+		patterns:   []string{"transcribe_minutes", "create_video_chapters", "extract_wisdom", "analyze_sales_call", "analyze_debate"},
+		tags:       []string{"call transcript analysis", "debate transcript"},
+		categories: []string{"Text Processing and Summarization"},
+	},
+	{
+		label: "source code",
+		detect: func(lines []string) bool {
+			return len(lines) >= 3 && countMatching(lines, codeLine)*10 >= len(lines)*3
+		},
+		patterns:   []string{"explain_code", "coding_master", "create_coding_project"},
+		tags:       []string{"code examples", "code generation", "programming concepts"},
+		categories: []string{"Code and Development"},
+	},
+}
+
+func countMatching(lines []string, re *regexp.Regexp) int {
+	n := 0
+	for _, line := range lines {
+		if re.MatchString(line) {
+			n++
+		}
+	}
+	return n
+}
+
+// detectContent returns the first rule that recognises the input.
+func detectContent(input []byte) (contentRule, bool) {
+	if len(input) > suggestSampleLimit {
+		input = input[:suggestSampleLimit]
+	}
+	var lines []string
+	for _, line := range strings.Split(string(input), "\n") {
+		if line = strings.TrimRight(line, " \t\r"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	for _, rule := range contentRules {
+		if rule.detect(lines) {
+			return rule, true
+		}
+	}
+	return contentRule{}, false
+}
+
+// suggest returns the dir_names of the patterns that best suit the rule:
+// the ones it names first, then the ones sharing most of its tags and
+// categories.
+func (rule contentRule) suggest(patterns []list.Item) []string {
+	var suggested []string
+	seen := map[string]bool{}
+	for _, dirName := range rule.patterns {
+		for _, item := range patterns {
+			if pattern := item.(Pattern); pattern.DirName == dirName && !seen[dirName] {
+				suggested = append(suggested, dirName)
+				seen[dirName] = true
+			}
+		}
+	}
+
+	// Tags count double, since categories are broad.
+	for score := 2*len(rule.tags) + len(rule.categories); score > 0 && len(suggested) < maxSuggestions; score-- {
+		for _, item := range patterns {
+			pattern := item.(Pattern)
+			if seen[pattern.DirName] || rule.score(pattern) != score {
+				continue
+			}
+			suggested = append(suggested, pattern.DirName)
+			seen[pattern.DirName] = true
+			if len(suggested) == maxSuggestions {
+				break
+			}
+		}
+	}
+	if len(suggested) > maxSuggestions {
+		suggested = suggested[:maxSuggestions]
+	}
+	return suggested
+}
+
+func (rule contentRule) score(pattern Pattern) int {
+	score := 0
+	for _, tag := range rule.tags {
+		if containsFold(pattern.Tags, tag) {
+			score += 2
+		}
+	}
+	for _, category := range rule.categories {
+		if containsFold(pattern.Categories, category) {
+			score++
+		}
+	}
+	return score
+}
+
+// patternSuggestions is shared with the list delegate and holds the
+// patterns suggested for the current input.
+type patternSuggestions struct {
+	label    string
+	dirNames []string
+}
+
+func (s *patternSuggestions) has(dirName string) bool {
+	for _, d := range s.dirNames {
+		if d == dirName {
+			return true
+		}
+	}
+	return false
+}
+
+// suggestionsMsg carries the suggestions for the input, tagged with the
+// request they answer.
+type suggestionsMsg struct {
+	request  int
+	label    string
+	dirNames []string
+}
+
+// suggestPatterns inspects the input again after the source changed.
+func (m *model) suggestPatterns() tea.Cmd {
+	m.suggestRequest++
+	return m.inspectInput(m.suggestRequest)
+}
+
+// inspectInput reads the pending input in the background and suggests
+// patterns for it. Piped stdin is left alone in print mode, where it is not
+// FabricForge's to read.
+func (m model) inspectInput(request int) tea.Cmd {
+	source, patterns := m.inputSource, m.loadedPatterns
+	if source == nil || (m.printMode && source.Kind() == InputStdin) {
+		return nil
+	}
+	return func() tea.Msg {
+		input, err := source.Read()
+		if err != nil {
+			return suggestionsMsg{request: request}
+		}
+		rule, ok := detectContent(input)
+		if !ok {
+			return suggestionsMsg{request: request}
+		}
+		return suggestionsMsg{request: request, label: rule.label, dirNames: rule.suggest(patterns)}
+	}
+}
+
+// applySuggestions moves the suggested patterns to the top of the list.
+func (m *model) applySuggestions(msg suggestionsMsg) {
+	if msg.request != m.suggestRequest {
+		// The input changed while it was being inspected.
+		return
+	}
+	unfiltered := len(m.filteredItems) == len(m.allPatterns)
+	m.suggestions.label = msg.label
+	m.suggestions.dirNames = msg.dirNames
+
+	top := make([]list.Item, 0, len(m.loadedPatterns))
+	for _, dirName := range msg.dirNames {
+		for _, item := range m.loadedPatterns {
+			if item.(Pattern).DirName == dirName {
+				top = append(top, item)
+			}
+		}
+	}
+	for _, item := range m.loadedPatterns {
+		if !m.suggestions.has(item.(Pattern).DirName) {
+			top = append(top, item)
+		}
+	}
+	m.allPatterns = top
+
+	if unfiltered && m.state == "selecting" {
+		m.filteredItems = m.allPatterns
+		m.list.SetItems(m.filteredItems)
+		m.list.Select(0)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestContentRulesMatchMetadata checks that every pattern, tag and category
+// named by a content rule exists in the pattern metadata.
+func TestContentRulesMatchMetadata(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "metadata", "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no metadata found: %v", err)
+	}
+	dirNames, tags, categories := map[string]bool{}, map[string]bool{}, map[string]bool{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var pattern Pattern
+		if err := json.Unmarshal(data, &pattern); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		dirNames[pattern.DirName] = true
+		for _, tag := range pattern.Tags {
+			tags[tag] = true
+		}
+		for _, category := range pattern.Categories {
+			categories[category] = true
+		}
+	}
+
+	for _, rule := range contentRules {
+		for _, dirName := range rule.patterns {
+			if !dirNames[dirName] {
+				t.Errorf("%s: no pattern %q", rule.label, dirName)
+			}
+		}
+		for _, tag := range rule.tags {
+			if !tags[tag] {
+				t.Errorf("%s: no pattern has the tag %q", rule.label, tag)
+			}
+		}
+		for _, category := range rule.categories {
+			if !categories[category] {
+				t.Errorf("%s: no pattern is in the category %q", rule.label, category)
+			}
+		}
+	}
+}
+
+func TestDetectContent(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name: "email headers",
+			input: "Received: from mail.example.com\n" +
+				"Authentication-Results: spf=pass\n" +
+				"DKIM-Signature: v=1; a=rsa-sha256\n" +
+				"Subject: hello\n",
+			want: "email headers",
+		},
+		{
+			name: "git diff",
+			input: "diff --git a/main.go b/main.go\n" +
+				"index 3b18e51..a9c4d2f 100644\n" +
+				"--- a/main.go\n" +
+				"+++ b/main.go\n" +
+				"@@ -1,3 +1,4 @@\n" +
+				" package main\n" +
+				"+import \"fmt\"\n",
+			want: "a git diff",
+		},
+		{
+			name:  "urls",
+			input: "https://example.com/a\n- https://example.com/b\n<https://example.com/c>\n",
+			want:  "a list of URLs",
+		},
+		{
+			name:  "webvtt",
+			input: "WEBVTT\n\n00:00.000 --> 00:02.000\nHello there\n",
+			want:  "a transcript",
+		},
+		{
+			name:  "speakers",
+			input: "Alice: Thanks for joining.\nBob: Happy to be here.\nAlice: Let's start with pricing.\n",
+			want:  "a transcript",
+		},
+		{
+			name:  "source code",
+			input: "package main\n\nfunc main() {\n\tx := 1\n\tprintln(x)\n}\n",
+			want:  "source code",
+		},
+		{
+			name:  "prose",
+			input: "The quick brown fox jumps over the lazy dog.\nIt was a sunny afternoon.\nNobody noticed.\n",
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, tt := range tests {
+		rule, ok := detectContent([]byte(tt.input))
+		if got := rule.label; got != tt.want || ok != (tt.want != "") {
+			t.Errorf("%s: detectContent = %q, %v, want %q", tt.name, got, ok, tt.want)
+		}
+	}
+}
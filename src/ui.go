@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/list"
@@ -27,61 +28,275 @@ var (
 			BorderStyle(lipgloss.NormalBorder()).   // Add a border
 			BorderForeground(lipgloss.Color("#FFFFFF")).  // White border color
 			Margin(1, 0)  // Add some vertical margin
+
+	errorStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FF5F5F"))
+
+	statusStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#7D56F4")).
+			Bold(true)
 )
 
 func (m model) Init() tea.Cmd {
-	return textinput.Blink
+	return tea.Batch(textinput.Blink, m.inspectInput(m.suggestRequest))
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
+	case runOutputMsg, runFinishedMsg, runTickMsg:
+		return m.updateRun(msg)
+	case postRunDoneMsg:
+		m.actionStatus = msg.status
+		m.inputErr = msg.err
+		return m, nil
+	case suggestionsMsg:
+		m.applySuggestions(msg)
+		return m, nil
 	case tea.KeyMsg:
+		if m.state == "executing" {
+			return m.updateExecuting(msg)
+		}
+		if m.state == "editing_options" {
+			return m.updateOptions(msg)
+		}
+		if m.state == "editing_variables" {
+			return m.updateVariables(msg)
+		}
+		if m.state == "editing_group_variables" {
+			return m.updateGroupVariables(msg)
+		}
+		if m.state == "history" {
+			return m.updateHistory(msg)
+		}
+		if m.state == "outputs" {
+			return m.updateOutputs(msg)
+		}
+		if m.state == "viewing" {
+			return m.updateViewer(msg)
+		}
+		if m.state == "searching_outputs" {
+			return m.updateOutputSearch(msg)
+		}
+		if m.state == "comparing" {
+			return m.updateComparison(msg)
+		}
+		if m.state == "facets" {
+			return m.updateFacets(msg)
+		}
+		if m.state == "post_run" {
+			return m.updatePostRun(msg)
+		}
+		if m.state == "post_run_save" {
+			return m.updatePostRunSave(msg)
+		}
+		if m.state == "saving_search" {
+			return m.updateSaveSearch(msg)
+		}
 		switch msg.String() {
-		case "ctrl+c", "q":
+		case "ctrl+c":
 			return m, tea.Quit
+		case "q":
+			if !m.typing() {
+				return m, tea.Quit
+			}
 		case "/":
 			if m.state == "selecting" {
+				// Keep the list's own filter closed so that the menu keys
+				// reach the menu.
 				m.state = "filter_menu"
-				m.list.SetItems(m.filterOptions)
+				m.list.SetItems(m.filterMenuItems())
+				m.list.Select(0)
+				return m, nil
+			}
+		case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			if search, ok := m.savedSearchKey(msg.String()); ok && (m.state == "selecting" || m.state == "filter_menu") {
+				m.applySavedSearch(search)
+				return m, nil
+			}
+		case "S", "ctrl+s":
+			// S would be typed into the search box while filtering.
+			if m.currentSearch.Filter != "" && (m.state == "selecting" || m.state == "filtering" && msg.String() == "ctrl+s") {
+				return m, m.openSaveSearch()
+			}
+		case "h":
+			if m.state == "selecting" {
+				m.openHistory()
+				return m, nil
+			}
+		case "backspace":
+			if m.state == "selecting" && m.facets.active() {
+				m.facets.removeCurrent()
+				m.applyFacets()
+				return m, nil
+			}
+		case "i":
+			if pattern, ok := m.list.SelectedItem().(Pattern); ok && m.state == "selecting" {
+				m.viewPatternInfo(pattern)
+				return m, nil
+			}
+		case "o":
+			if m.state == "selecting" {
+				m.openOutputs()
+				return m, nil
+			}
+		case "s":
+			if m.state == "selecting" {
+				return m, m.openOutputSearch()
+			}
+		case " ":
+			if m.state == "selecting" && m.list.SelectedItem() != nil {
+				m.marks.toggle(m.list.SelectedItem().(Pattern))
+				return m, nil
+			}
+		case "c":
+			if m.state == "selecting" && len(m.marks.order) > 0 {
+				m.state = "chain_builder"
+				m.list.SetItems(m.marks.items())
+				m.list.Select(0)
+				return m, nil
+			}
+		case "f":
+			if (m.state == "selecting" || m.state == "chain_builder") && len(m.marks.order) > 0 {
+				return m.runMarked(true)
+			}
+		case "K", "shift+up", "J", "shift+down":
+			if m.state == "chain_builder" {
+				delta := 1
+				if msg.String() == "K" || msg.String() == "shift+up" {
+					delta = -1
+				}
+				index := m.marks.move(m.list.Index(), delta)
+				m.list.SetItems(m.marks.items())
+				m.list.Select(index)
+				return m, nil
+			}
+		case "x", "delete":
+			if item, ok := m.list.SelectedItem().(savedSearchItem); ok && m.state == "filter_menu" {
+				if err := m.searches.remove(item.search.Name); err != nil {
+					m.inputErr = fmt.Errorf("removing saved search: %w", err)
+				}
+				index := m.list.Index()
+				m.list.SetItems(m.filterMenuItems())
+				m.list.Select(min(index, len(m.list.Items())-1))
+				return m, nil
+			}
+			if m.state == "chain_builder" {
+				m.marks.remove(m.list.Index())
+				if len(m.marks.order) == 0 {
+					m.state = "selecting"
+					m.list.SetItems(m.filteredItems)
+				} else {
+					m.list.SetItems(m.marks.items())
+				}
+				return m, nil
 			}
 		case "esc":
-			if m.state == "filtering" || m.state == "filter_menu" {
+			if m.state == "selecting" || m.state == "confirming" {
+				return m, tea.Quit
+			} else if m.state == "chain_builder" {
+				m.state = "selecting"
+				m.list.SetItems(m.filteredItems)
+				return m, nil
+			} else if m.state == "filtering" || m.state == "filter_menu" {
 				m.state = "selecting"
 				m.filteredItems = m.allPatterns
+				m.highlights.set(nil)
+				m.facets.clear()
+				m.currentSearch = savedSearch{}
+				m.inputErr = nil
 				m.list.SetItems(m.filteredItems)
 				m.textInput.SetValue("")
+			} else if m.state == "selecting_input" || m.state == "entering_input" {
+				m.textInput.Placeholder = m.config.Placeholder
+				m.textInput.SetValue("")
+				m.state = "confirming"
+				m.list.SetItems(m.confirmItems())
+				return m, nil
 			}
 		case "enter":
 			switch m.state {
 			case "selecting":
 				if m.list.SelectedItem() != nil {
-					selectedPattern := m.list.SelectedItem().(Pattern)
-					m.selectedCmd = m.buildFabricCommand(selectedPattern)
-					m.state = "confirming"
-					m.list.SetItems(m.confirmItems)
+					m.choosePattern(m.list.SelectedItem().(Pattern))
 				}
+			case "chain_builder":
+				return m.runMarked(false)
 			case "confirming":
 				if m.list.SelectedItem() != nil {
 					choice := m.list.SelectedItem().(confirmItem)
-					if choice.title == "Yes" {
-						m.state = "executing"
-						return m, tea.Quit
-					} else {
+					switch choice.title {
+					case "Yes":
+						if m.invocation.Chat != nil {
+							if m.inputErr = m.invocation.Chat.check(); m.inputErr != nil {
+								return m, nil
+							}
+						}
+						return m.execute([]Invocation{m.invocation})
+					case "Input source":
+						m.state = "selecting_input"
+						m.list.SetItems(m.inputItems)
+					case "Options":
+						m.state = "editing_options"
+						m.optionsForm = newOptionsForm(m.patternOptions(m.selected.DirName), m.selected, m.config.StreamResults)
+						return m, textinput.Blink
+					case "Variables":
+						m.editVariables()
+						return m, textinput.Blink
+					default:
 						m.state = "selecting"
 						m.list.SetItems(m.filteredItems)
 					}
 				}
+			case "selecting_input":
+				if m.list.SelectedItem() != nil {
+					m.inputKind = m.list.SelectedItem().(inputSourceItem).kind
+					m.inputErr = nil
+					switch m.inputKind {
+					case InputFile, InputText:
+						m.state = "entering_input"
+						m.textInput.SetValue("")
+						if m.inputKind == InputFile {
+							m.textInput.Placeholder = "Path to the input file"
+						} else {
+							m.textInput.Placeholder = "Text to send to fabric"
+						}
+						return m, nil
+					default:
+						return m, m.setInputSource(m.inputKind, "")
+					}
+				}
+			case "entering_input":
+				value := m.textInput.Value()
+				if m.inputKind == InputFile {
+					if _, err := os.Stat(value); err != nil {
+						m.inputErr = err
+						return m, nil
+					}
+				}
+				m.textInput.Placeholder = m.config.Placeholder
+				m.textInput.SetValue("")
+				return m, m.setInputSource(m.inputKind, value)
 			case "filter_menu":
+				if item, ok := m.list.SelectedItem().(savedSearchItem); ok {
+					m.applySavedSearch(item.search)
+					return m, nil
+				}
 				if m.list.SelectedItem() != nil {
 					filterOption := m.list.SelectedItem().(FilterOption)
+					if filterOption.Name == "Facets" {
+						m.openFacets()
+						return m, nil
+					}
+					m.facets.clear()
 					m.currentFilter = filterOption.Name
 					m.state = "filtering"
 					m.textInput.SetValue("")
 					m.textInput.Focus()
-					if m.currentFilter == "Global Search" {
+					if m.searchFilter() {
 						m.filteredItems = m.allPatterns
+						m.highlights.set(nil)
 						m.list.SetItems(m.filteredItems)
 					} else {
 						var filterItems []list.Item
@@ -97,20 +312,19 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 				}
 			case "filtering":
-				if m.currentFilter == "Global Search" {
+				if m.searchFilter() {
 					if m.list.SelectedItem() != nil {
-						selectedPattern := m.list.SelectedItem().(Pattern)
-						m.selectedCmd = m.buildFabricCommand(selectedPattern)
-						m.state = "confirming"
-						m.list.SetItems(m.confirmItems)
+						m.choosePattern(m.list.SelectedItem().(Pattern))
 					} else {
-						m.filteredItems = filterPatterns(m.allPatterns, m.textInput.Value())
+						m.applySearch()
 						m.state = "selecting"
 						m.list.SetItems(m.filteredItems)
 					}
 				} else if m.list.SelectedItem() != nil {
 					selectedFilter := m.list.SelectedItem().(FilterOption).Name
 					m.filteredItems = filterPatternsByMetadata(m.allPatterns, m.currentFilter, selectedFilter)
+					m.currentSearch = savedSearch{Filter: m.currentFilter, Query: selectedFilter}
+					m.highlights.set(nil)
 					m.state = "selecting"
 					m.list.SetItems(m.filteredItems)
 				}
@@ -121,14 +335,22 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		h, v := appStyle.GetFrameSize()
 		m.list.SetSize(msg.Width-h, msg.Height-v-6)
 		m.textInput.Width = msg.Width - h - 4
+		m.viewport.Width = msg.Width - h
+		m.height = msg.Height - v
+		m.resizeList()
+		m.resizeViewport()
+		m.resizeViewer()
+		m.resizeComparison()
 	}
 
-	if m.state == "filtering" && m.currentFilter == "Global Search" {
-		m.filteredItems = filterPatterns(m.allPatterns, m.textInput.Value())
-		m.list.SetItems(m.filteredItems)
+	query := m.textInput.Value()
+	m.textInput, cmd = m.textInput.Update(msg)
+
+	if m.state == "filtering" && m.searchFilter() && m.textInput.Value() != query {
+		m.applySearch()
+		m.list.Select(0)
 	}
 
-	m.textInput, cmd = m.textInput.Update(msg)
 	m.list, cmd = m.list.Update(msg)
 
 	return m, cmd
@@ -139,28 +361,128 @@ func (m model) View() string {
 	switch m.state {
 	case "selecting":
 		content = lipgloss.JoinVertical(lipgloss.Left,
-			"Select a pattern (↑/↓ to navigate, enter to select, / to filter):",
+			"Select a pattern (↑/↓ to navigate, enter to select, / to filter, space to mark for a chain, i for details, h for history, o for saved outputs, s to search them):",
 			m.list.View(),
 		)
+		if len(m.marks.order) > 0 {
+			content = lipgloss.JoinVertical(lipgloss.Left,
+				content,
+				fmt.Sprintf("Marked: %s (c to build a chain, f to fan out)", m.marks.describe()),
+			)
+		}
+		if m.facets.active() {
+			content = lipgloss.JoinVertical(lipgloss.Left,
+				content,
+				m.facets.chips()+facetDimStyle.Render("  (backspace to remove, S to save this search)"),
+			)
+		}
+		if m.currentSearch.Filter != "" && m.currentSearch.Filter != "Facets" {
+			content = lipgloss.JoinVertical(lipgloss.Left,
+				content,
+				facetDimStyle.Render(fmt.Sprintf("Filtered by %s (S to save this search)", m.currentSearch.describe())),
+			)
+		}
+		if m.inputErr != nil {
+			content = lipgloss.JoinVertical(lipgloss.Left, errorStyle.Render(m.inputErr.Error()), content)
+		}
+		if m.suggestions.label != "" {
+			content = lipgloss.JoinVertical(lipgloss.Left,
+				content,
+				facetDimStyle.Render(fmt.Sprintf("★ suggested because the input looks like %s", m.suggestions.label)),
+			)
+		}
 	case "confirming":
 		content = lipgloss.JoinVertical(lipgloss.Left,
 			"Command to execute:",
-			commandStyle.Render(m.selectedCmd),
+			commandStyle.Render(m.invocation.String()),
 			"Do you want to execute this command?",
 			m.list.View(),
 		)
+		if m.inputErr != nil {
+			content = lipgloss.JoinVertical(lipgloss.Left, errorStyle.Render(m.inputErr.Error()), content)
+		}
+	case "history":
+		content = m.historyView()
+	case "outputs":
+		content = m.outputsView()
+	case "viewing":
+		content = m.viewerView()
+	case "comparing":
+		content = m.comparisonView()
+	case "facets":
+		content = m.facetsView()
+	case "searching_outputs":
+		content = m.outputSearchView()
+	case "post_run", "post_run_save":
+		content = m.postRunView()
+	case "editing_variables":
+		content = lipgloss.JoinVertical(lipgloss.Left,
+			fmt.Sprintf("Template variables for %s (tab/↑/↓ to move, enter to save, esc to cancel):", m.selected.DirName),
+			commandStyle.Render(m.invocation.String()),
+			m.variablesForm.view(),
+		)
+	case "editing_group_variables":
+		content = m.groupVariablesView()
+	case "editing_options":
+		content = lipgloss.JoinVertical(lipgloss.Left,
+			fmt.Sprintf("Fabric options for %s (tab/↑/↓ to move, space toggles stream, enter to save, esc to cancel):", m.selected.DirName),
+			commandStyle.Render(m.invocation.String()),
+			m.optionsForm.view(),
+		)
+	case "selecting_input":
+		content = lipgloss.JoinVertical(lipgloss.Left,
+			"Select input source (enter to select, esc to cancel):",
+			m.list.View(),
+		)
+	case "entering_input":
+		prompt := "Enter the text to send to fabric (enter to confirm, esc to cancel):"
+		if m.inputKind == InputFile {
+			prompt = "Enter the path of the input file (enter to confirm, esc to cancel):"
+		}
+		content = lipgloss.JoinVertical(lipgloss.Left,
+			prompt,
+			m.textInput.View(),
+		)
+		if m.inputErr != nil {
+			content = lipgloss.JoinVertical(lipgloss.Left, content, errorStyle.Render(m.inputErr.Error()))
+		}
+	case "executing":
+		content = m.executionView()
+	case "chain_builder":
+		content = lipgloss.JoinVertical(lipgloss.Left,
+			fmt.Sprintf("Chain (input: %s):", m.inputSource.Label()),
+			commandStyle.Render(m.marks.describe()),
+			"Reorder with shift+↑/↓, x to remove, enter to run the chain, f to fan out instead, esc to go back:",
+			m.list.View(),
+		)
 	case "filter_menu":
+		title := "Select filter type:"
+		if len(m.searches.Searches) > 0 {
+			title = "Select filter type or saved search (1-9 to apply a saved search, x to delete one):"
+		}
 		content = lipgloss.JoinVertical(lipgloss.Left,
-			"Select filter type:",
+			title,
 			m.list.View(),
 		)
+		if m.inputErr != nil {
+			content = lipgloss.JoinVertical(lipgloss.Left, errorStyle.Render(m.inputErr.Error()), content)
+		}
+	case "saving_search":
+		content = m.saveSearchView()
 	case "filtering":
-		if m.currentFilter == "Global Search" {
+		if m.searchFilter() {
+			prompt := fmt.Sprintf("Filter %s (type to filter, ↑/↓ to select, enter to confirm, ctrl+s to save the search):", m.currentFilter)
+			if m.currentFilter == "Describe" {
+				prompt = "Describe what you want, e.g. \"turn a meeting transcript into action items\" (↑/↓ to select, enter to confirm, ctrl+s to save the search):"
+			}
 			content = lipgloss.JoinVertical(lipgloss.Left,
-				fmt.Sprintf("Filter %s (type to filter, ↑/↓ to select, enter to confirm):", m.currentFilter),
+				prompt,
 				m.textInput.View(),
 				m.list.View(),
 			)
+			if m.inputErr != nil {
+				content = lipgloss.JoinVertical(lipgloss.Left, content, errorStyle.Render(m.inputErr.Error()))
+			}
 		} else {
 			content = lipgloss.JoinVertical(lipgloss.Left,
 				fmt.Sprintf("Select %s (↑/↓ to navigate, enter to select, esc to cancel):", m.currentFilter),
@@ -175,23 +497,197 @@ func (m model) View() string {
 	))
 }
 
-func filterPatterns(patterns []list.Item, filter string) []list.Item {
-	if filter == "" {
-		return patterns
+// choosePattern selects a pattern from the list. Patterns with template
+// variables ask for their values first.
+func (m *model) choosePattern(pattern Pattern) {
+	m.chooseRerun(pattern, nil)
+}
+
+// chooseRerun selects a pattern with the options and input of a history
+// entry, or with the saved ones if rerun is nil.
+func (m *model) chooseRerun(pattern Pattern, rerun *historyRerun) {
+	m.rerun = rerun
+	m.variables = patternVariables(m.config.PatternsDir, pattern)
+	m.selectPattern(pattern)
+	if len(m.variables) > 0 {
+		m.editVariables()
+	}
+}
+
+// editVariables opens the template variables form for the selected pattern.
+func (m *model) editVariables() {
+	m.state = "editing_variables"
+	m.variablesForm = newVariablesForm(m.variables, m.patternOptions(m.selected.DirName).Variables)
+}
+
+// selectPattern moves to the confirmation screen for the given pattern.
+func (m *model) selectPattern(pattern Pattern) {
+	m.inputErr = nil
+	m.selected = pattern
+	m.invocation = m.buildFabricCommand(pattern)
+	if m.invocation.Chat != nil {
+		m.inputErr = m.invocation.Chat.check()
+	}
+	m.state = "confirming"
+	m.list.SetItems(m.confirmItems())
+}
+
+// updateOptions handles key presses in the fabric options form, keeping the
+// command preview in sync with the form.
+func (m model) updateOptions(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.selectPattern(m.selected)
+		return m, nil
+	case "enter":
+		options := m.optionsForm.options()
+		if err := options.validate(); err != nil {
+			m.optionsForm.err = err
+			return m, nil
+		}
+		if err := m.setPatternOptions(m.selected.DirName, options); err != nil {
+			m.optionsForm.err = fmt.Errorf("saving options: %w", err)
+			return m, nil
+		}
+		m.selectPattern(m.selected)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.optionsForm, cmd = m.optionsForm.update(msg)
+	m.optionsForm.err = m.optionsForm.options().validate()
+	m.invocation = m.buildFabricCommandWith(m.selected, m.optionsForm.options())
+	return m, cmd
+}
+
+// updateVariables handles key presses in the template variables form,
+// keeping the command preview in sync with the form.
+func (m model) updateVariables(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	options := m.patternOptions(m.selected.DirName)
+	options.Variables = m.variablesForm.values()
+
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.selectPattern(m.selected)
+		return m, nil
+	case "enter":
+		if err := m.setPatternOptions(m.selected.DirName, options); err != nil {
+			m.inputErr = fmt.Errorf("saving variables: %w", err)
+		}
+		m.selectPattern(m.selected)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.variablesForm, cmd = m.variablesForm.update(msg)
+	options.Variables = m.variablesForm.values()
+	m.invocation = m.buildFabricCommandWith(m.selected, options)
+	return m, cmd
+}
+
+// setInputSource switches the input source and returns to the confirmation
+// screen with the command rebuilt. The returned command refreshes the
+// pattern suggestions for the new input.
+func (m *model) setInputSource(kind, value string) tea.Cmd {
+	source, err := newInputSource(kind, value)
+	if err != nil {
+		m.inputErr = err
+		return nil
+	}
+	m.inputSource = source
+	if m.rerun != nil {
+		m.rerun.input = nil
 	}
+	m.selectPattern(m.selected)
+	return m.suggestPatterns()
+}
+
+// typing reports whether keys go to the text input, so that letters such
+// as q are typed rather than taken as commands.
+func (m *model) typing() bool {
+	switch m.state {
+	case "entering_input", "saving_search", "post_run_save":
+		return true
+	case "filtering":
+		return m.searchFilter()
+	}
+	return false
+}
+
+// searchFilter reports whether the current filter is typed in the text
+// input rather than picked from a list.
+func (m *model) searchFilter() bool {
+	return m.currentFilter == "Global Search" || m.currentFilter == "Describe"
+}
 
+// applySearch shows the patterns that match the text input, using the
+// relevance index in Describe mode.
+func (m *model) applySearch() {
+	query := m.textInput.Value()
+	if m.currentFilter == "Describe" {
+		m.inputErr = nil
+		m.filteredItems = m.allPatterns
+		m.highlights.set(nil)
+		if strings.TrimSpace(query) != "" {
+			items, matches := m.relevance.search(query)
+			m.filteredItems = items
+			m.highlights.set(matches)
+		}
+		m.list.SetItems(m.filteredItems)
+	} else {
+		m.applyGlobalSearch()
+		if m.inputErr != nil {
+			return
+		}
+	}
+
+	m.currentSearch = savedSearch{}
+	if strings.TrimSpace(query) != "" {
+		m.currentSearch = savedSearch{Filter: m.currentFilter, Query: query}
+	}
+}
+
+// applyGlobalSearch shows the patterns that match the text input. An
+// invalid query leaves the list as it was.
+func (m *model) applyGlobalSearch() {
+	items, matches, err := filterPatterns(m.allPatterns, m.textInput.Value())
+	m.inputErr = err
+	if err != nil {
+		return
+	}
+	m.filteredItems = items
+	m.highlights.set(matches)
+	m.list.SetItems(m.filteredItems)
+}
+
+// filterPatterns filters the patterns with a query. Plain words are fuzzy
+// matched, best match first, and the matched characters are returned for
+// highlighting. Queries that use field qualifiers or operators (see
+// parseQuery) are applied as a predicate and keep the list order.
+func filterPatterns(patterns []list.Item, filter string) ([]list.Item, map[string]patternMatch, error) {
+	if strings.TrimSpace(filter) == "" {
+		return patterns, nil, nil
+	}
+	if !isStructuredQuery(filter) {
+		items, matches := rankPatterns(patterns, filter)
+		return items, matches, nil
+	}
+
+	predicate, err := parseQuery(filter)
+	if err != nil {
+		return nil, nil, err
+	}
 	var filtered []list.Item
 	for _, item := range patterns {
-		pattern := item.(Pattern)
-		if strings.Contains(strings.ToLower(pattern.FriendlyName), strings.ToLower(filter)) ||
-			strings.Contains(strings.ToLower(pattern.DirName), strings.ToLower(filter)) ||
-			strings.Contains(strings.ToLower(pattern.ShortDesc), strings.ToLower(filter)) ||
-			containsInSlice(pattern.Categories, filter) ||
-			containsInSlice(pattern.Tags, filter) {
-			filtered = append(filtered, pattern)
+		if predicate(item.(Pattern)) {
+			filtered = append(filtered, item)
 		}
 	}
-	return filtered
+	return filtered, nil, nil
 }
 
 func filterPatternsByMetadata(patterns []list.Item, filterType, filterValue string) []list.Item {
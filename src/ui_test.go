@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// quits reports whether running cmd, and any commands it batches, ends the
+// program.
+func quits(cmd tea.Cmd) bool {
+	if cmd == nil {
+		return false
+	}
+	switch msg := cmd().(type) {
+	case tea.QuitMsg:
+		return true
+	case tea.BatchMsg:
+		for _, c := range msg {
+			if quits(c) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func testModel(t *testing.T) model {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	patterns := []list.Item{
+		Pattern{DirName: "summarize", FriendlyName: "Summarize", ShortDesc: "Summarizes content"},
+	}
+	return initialModel(patterns, Config{Width: 80, Height: 24}, ClipboardSource{})
+}
+
+func press(m model, key string) (model, tea.Cmd) {
+	msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+	switch key {
+	case "enter":
+		msg = tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		msg = tea.KeyMsg{Type: tea.KeyEsc}
+	}
+	next, cmd := m.Update(msg)
+	return next.(model), cmd
+}
+
+func TestTypingQDoesNotQuit(t *testing.T) {
+	for _, filter := range []string{"Global Search", "Describe"} {
+		m := testModel(t)
+		m, _ = press(m, "/")
+		for i, item := range m.list.Items() {
+			if option, ok := item.(FilterOption); ok && option.Name == filter {
+				m.list.Select(i)
+			}
+		}
+		m, _ = press(m, "enter")
+		if m.state != "filtering" || !m.typing() {
+			t.Fatalf("%s: state %q after choosing the filter", filter, m.state)
+		}
+
+		m, cmd := press(m, "q")
+		if quits(cmd) {
+			t.Errorf("%s: typing q quit the program", filter)
+		}
+		if m.textInput.Value() != "q" {
+			t.Errorf("%s: search box holds %q, want %q", filter, m.textInput.Value(), "q")
+		}
+	}
+
+	m := testModel(t)
+	if _, cmd := press(m, "q"); !quits(cmd) {
+		t.Error("q in the pattern list did not quit")
+	}
+}
+
+func TestEscLeavesInputScreens(t *testing.T) {
+	for _, state := range []string{"selecting_input", "entering_input"} {
+		m := testModel(t)
+		m, _ = press(m, "enter")
+		if m.state != "confirming" {
+			t.Fatalf("state %q after choosing a pattern", m.state)
+		}
+		m.state = state
+		m.list.SetItems(m.inputItems)
+
+		m, cmd := press(m, "esc")
+		if quits(cmd) {
+			t.Errorf("esc in %s quit the program", state)
+		}
+		if m.state != "confirming" {
+			t.Errorf("esc in %s went to %q, want confirming", state, m.state)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var templateVariable = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// patternVariables returns the {{name}} placeholders used in the pattern's
+// system.md and user.md, in order of first appearance. {{input}} is filled
+// with the input by fabric and is skipped.
+func patternVariables(patternsDir string, pattern Pattern) []string {
+	if patternsDir == "" {
+		return nil
+	}
+
+	seen := map[string]bool{"input": true}
+	var names []string
+	for _, file := range []string{"system.md", "user.md"} {
+		data, err := os.ReadFile(filepath.Join(patternsDir, pattern.DirName, file))
+		if err != nil {
+			continue
+		}
+		for _, match := range templateVariable.FindAllStringSubmatch(string(data), -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				names = append(names, match[1])
+			}
+		}
+	}
+	return names
+}
+
+// applyVariables replaces the {{name}} placeholders in text with the given
+// values, leaving unknown placeholders as they are.
+func applyVariables(text string, values map[string]string) string {
+	return templateVariable.ReplaceAllStringFunc(text, func(placeholder string) string {
+		name := templateVariable.FindStringSubmatch(placeholder)[1]
+		if value, ok := values[name]; ok && value != "" {
+			return value
+		}
+		return placeholder
+	})
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// variablesSummary renders the values as "name=value, ..." in the order of
+// names.
+func variablesSummary(names []string, values map[string]string) string {
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + values[name]
+	}
+	return strings.Join(parts, ", ")
+}
+
+// variablesForm prompts for the value of each template variable.
+type variablesForm struct {
+	names  []string
+	inputs []textinput.Model
+	focus  int
+}
+
+func newVariablesForm(names []string, values map[string]string) variablesForm {
+	f := variablesForm{names: names, inputs: make([]textinput.Model, len(names))}
+	for i, name := range names {
+		f.inputs[i] = textinput.New()
+		f.inputs[i].Prompt = ""
+		f.inputs[i].Placeholder = "value for {{" + name + "}}"
+		f.inputs[i].SetValue(values[name])
+	}
+	if len(f.inputs) > 0 {
+		f.inputs[0].Focus()
+	}
+	return f
+}
+
+func (f variablesForm) values() map[string]string {
+	values := make(map[string]string, len(f.names))
+	for i, name := range f.names {
+		values[name] = strings.TrimSpace(f.inputs[i].Value())
+	}
+	return values
+}
+
+func (f variablesForm) update(msg tea.KeyMsg) (variablesForm, tea.Cmd) {
+	switch msg.String() {
+	case "tab", "down":
+		f.setFocus((f.focus + 1) % len(f.inputs))
+		return f, nil
+	case "shift+tab", "up":
+		f.setFocus((f.focus + len(f.inputs) - 1) % len(f.inputs))
+		return f, nil
+	}
+
+	var cmd tea.Cmd
+	f.inputs[f.focus], cmd = f.inputs[f.focus].Update(msg)
+	return f, cmd
+}
+
+func (f *variablesForm) setFocus(i int) {
+	f.inputs[f.focus].Blur()
+	f.focus = i
+	f.inputs[f.focus].Focus()
+}
+
+func (f variablesForm) view() string {
+	width := 0
+	for _, name := range f.names {
+		width = max(width, len(name))
+	}
+	lines := make([]string, len(f.names))
+	for i, name := range f.names {
+		lines[i] = formLabel(name, width, i == f.focus) + " " + f.inputs[i].View()
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	matchStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("#7D56F4"))
+	currentMatchStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FAFAFA")).Background(lipgloss.Color("#7D56F4"))
+)
+
+// outputViewer shows a Markdown document rendered for the terminal, with
+// search and navigation between section headings.
+type outputViewer struct {
+	title    string
+	source   string
+	raw      bool
+	doc      markdownDoc
+	viewport viewport.Model
+	search   textinput.Model
+	// searching is set while the query is being typed.
+	searching bool
+	query     string
+	matches   []int
+	match     int
+	// back is the state to return to.
+	back string
+}
+
+// openViewer shows the Markdown text in the viewer.
+func (m *model) openViewer(title, text string) {
+	search := textinput.New()
+	search.Prompt = "/"
+	m.viewer = outputViewer{
+		title:    title,
+		source:   text,
+		viewport: viewport.New(m.viewport.Width, max(m.height-4, 1)),
+		search:   search,
+		back:     m.state,
+	}
+	m.viewer.render()
+	m.state = "viewing"
+}
+
+// openOutputFile shows a saved output in the viewer.
+func (m *model) openOutputFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if m.config.FrontMatter {
+		content = stripFrontMatter(content)
+	}
+	m.openViewer(path, string(content))
+	return nil
+}
+
+// resizeViewer fits the viewer to the window and renders the document again
+// for the new width.
+func (m *model) resizeViewer() {
+	m.viewer.viewport.Width = m.viewport.Width
+	m.viewer.viewport.Height = max(m.height-4, 1)
+	if m.state == "viewing" {
+		m.viewer.render()
+	}
+}
+
+// render lays out the document for the current width and refreshes the
+// search matches.
+func (v *outputViewer) render() {
+	width := v.viewport.Width - 2
+	if v.raw {
+		v.doc = markdownDoc{}
+		for _, line := range strings.Split(v.source, "\n") {
+			v.doc.lines = append(v.doc.lines, mdLine{plain: line, styled: line})
+		}
+	} else {
+		v.doc = renderMarkdown(v.source, width)
+	}
+	v.find()
+	v.refresh()
+}
+
+// find collects the lines that contain the query.
+func (v *outputViewer) find() {
+	v.matches = nil
+	v.match = 0
+	if v.query == "" {
+		return
+	}
+	query := strings.ToLower(v.query)
+	for i, line := range v.doc.lines {
+		if strings.Contains(strings.ToLower(line.plain), query) {
+			v.matches = append(v.matches, i)
+		}
+	}
+}
+
+// refresh sets the viewport content, marking lines that match the search in
+// the gutter.
+func (v *outputViewer) refresh() {
+	current := -1
+	if len(v.matches) > 0 {
+		current = v.matches[v.match]
+	}
+	matched := make(map[int]bool, len(v.matches))
+	for _, line := range v.matches {
+		matched[line] = true
+	}
+
+	lines := make([]string, len(v.doc.lines))
+	for i, line := range v.doc.lines {
+		switch {
+		case i == current:
+			lines[i] = currentMatchStyle.Render("▌") + " " + line.styled
+		case matched[i]:
+			lines[i] = matchStyle.Render("▌") + " " + line.styled
+		default:
+			lines[i] = "  " + line.styled
+		}
+	}
+	v.viewport.SetContent(strings.Join(lines, "\n"))
+}
+
+// jumpToMatch moves to the match delta places from the current one.
+func (v *outputViewer) jumpToMatch(delta int) {
+	if len(v.matches) == 0 {
+		return
+	}
+	v.match = (v.match + delta + len(v.matches)) % len(v.matches)
+	v.refresh()
+	v.viewport.SetYOffset(v.matches[v.match] - v.viewport.Height/3)
+}
+
+// jumpToHeading scrolls to the next (delta 1) or previous (delta -1)
+// section heading.
+func (v *outputViewer) jumpToHeading(delta int) {
+	top := v.viewport.YOffset
+	if delta > 0 {
+		for _, heading := range v.doc.headings {
+			if heading.line > top {
+				v.viewport.SetYOffset(heading.line)
+				return
+			}
+		}
+		return
+	}
+	for i := len(v.doc.headings) - 1; i >= 0; i-- {
+		if v.doc.headings[i].line < top {
+			v.viewport.SetYOffset(v.doc.headings[i].line)
+			return
+		}
+	}
+	v.viewport.GotoTop()
+}
+
+// section returns the title of the section at the top of the viewport.
+func (v *outputViewer) section() string {
+	title := ""
+	for _, heading := range v.doc.headings {
+		if heading.line > v.viewport.YOffset {
+			break
+		}
+		title = heading.title
+	}
+	return title
+}
+
+// updateViewer handles key presses in the viewer.
+func (m model) updateViewer(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := &m.viewer
+	if v.searching {
+		switch msg.String() {
+		case "enter":
+			v.searching = false
+			v.query = v.search.Value()
+			v.find()
+			v.refresh()
+			// Start at the first match below the top of the screen.
+			for i, line := range v.matches {
+				if line >= v.viewport.YOffset {
+					v.match = i
+					break
+				}
+			}
+			v.jumpToMatch(0)
+			return m, nil
+		case "esc":
+			v.searching = false
+			return m, nil
+		}
+		var cmd tea.Cmd
+		v.search, cmd = v.search.Update(msg)
+		return m, cmd
+	}
+
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		if v.query != "" {
+			v.query = ""
+			v.find()
+			v.refresh()
+			return m, nil
+		}
+		m.state = v.back
+		return m, nil
+	case "/":
+		v.searching = true
+		v.search.SetValue(v.query)
+		v.search.CursorEnd()
+		return m, v.search.Focus()
+	case "n":
+		v.jumpToMatch(1)
+		return m, nil
+	case "N":
+		v.jumpToMatch(-1)
+		return m, nil
+	case "]", "tab":
+		v.jumpToHeading(1)
+		return m, nil
+	case "[", "shift+tab":
+		v.jumpToHeading(-1)
+		return m, nil
+	case "g", "home":
+		v.viewport.GotoTop()
+		return m, nil
+	case "G", "end":
+		v.viewport.GotoBottom()
+		return m, nil
+	case "r":
+		v.raw = !v.raw
+		v.render()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	v.viewport, cmd = v.viewport.Update(msg)
+	return m, cmd
+}
+
+func (m model) viewerView() string {
+	v := m.viewer
+	status := fmt.Sprintf("%3.f%%", v.viewport.ScrollPercent()*100)
+	if section := v.section(); section != "" {
+		status = section + "  " + status
+	}
+	if v.query != "" {
+		if len(v.matches) == 0 {
+			status += fmt.Sprintf("  no matches for %q", v.query)
+		} else {
+			status += fmt.Sprintf("  match %d/%d for %q", v.match+1, len(v.matches), v.query)
+		}
+	}
+
+	footer := "↑/↓ to scroll, [/] for previous/next section, / to search, n/N for next/previous match, r for raw text, esc to go back"
+	if v.searching {
+		footer = v.search.View()
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		statusStyle.Render(v.title),
+		v.viewport.View(),
+		statusStyle.Render(status),
+		footer,
+	)
+}
+
+// outputFile is a saved output listed in the output browser.
+type outputFile struct {
+	path     string
+	rel      string
+	modified time.Time
+	size     int64
+}
+
+func (f outputFile) Title() string { return f.rel }
+
+func (f outputFile) Description() string {
+	return fmt.Sprintf("%s, %d bytes", f.modified.Format("2006-01-02 15:04"), f.size)
+}
+
+func (f outputFile) FilterValue() string { return f.rel }
+
+// outputMatcher tells the outputs saved from patterns apart from the other
+// files that end up in the output directory: the merged metadata, exported
+// patches and temporary files.
+type outputMatcher struct {
+	// exts are the output extensions of the patterns, in lower case.
+	exts map[string]bool
+	// metadata is the merged metadata file, which may live in the output
+	// directory.
+	metadata string
+}
+
+// outputMatcher returns the matcher for the outputs of the loaded patterns.
+func (m *model) outputMatcher() outputMatcher {
+	match := outputMatcher{exts: map[string]bool{}}
+	for _, item := range m.loadedPatterns {
+		match.exts[strings.ToLower(item.(Pattern).outputExt())] = true
+	}
+	if m.config.MetadataPath != "" {
+		match.metadata, _ = filepath.Abs(m.config.MetadataPath)
+	}
+	return match
+}
+
+// matches reports whether the file at path is a saved pattern output.
+func (o outputMatcher) matches(path string) bool {
+	name := filepath.Base(path)
+	ext := strings.ToLower(filepath.Ext(name))
+	switch {
+	case strings.HasPrefix(name, "."), ext == ".tmp", ext == ".patch", name == "merged_patterns_metadata.json":
+		return false
+	case o.metadata != "":
+		if abs, err := filepath.Abs(path); err == nil && abs == o.metadata {
+			return false
+		}
+	}
+	return o.exts[ext]
+}
+
+// listOutputs returns the saved pattern outputs under dir, newest first.
+// Hidden directories are left out.
+func listOutputs(dir string, match outputMatcher) ([]outputFile, error) {
+	var files []outputFile
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !match.matches(path) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files = append(files, outputFile{path: path, rel: rel, modified: info.ModTime(), size: info.Size()})
+		return nil
+	})
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].modified.After(files[j].modified)
+	})
+	return files, err
+}
+
+// openOutputs shows the output browser.
+func (m *model) openOutputs() {
+	files, err := listOutputs(m.config.OutputDir, m.outputMatcher())
+	m.inputErr = err
+	items := make([]list.Item, len(files))
+	for i, file := range files {
+		items[i] = file
+	}
+	m.state = "outputs"
+	m.list.ResetFilter()
+	m.list.SetItems(items)
+	m.list.Select(0)
+}
+
+// updateOutputs handles key presses in the output browser.
+func (m model) updateOutputs(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.list.SettingFilter() {
+		var cmd tea.Cmd
+		m.list, cmd = m.list.Update(msg)
+		return m, cmd
+	}
+
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		if m.list.IsFiltered() {
+			m.list.ResetFilter()
+			return m, nil
+		}
+		m.inputErr = nil
+		m.state = "selecting"
+		m.list.SetItems(m.filteredItems)
+		return m, nil
+	case "enter":
+		if file, ok := m.list.SelectedItem().(outputFile); ok {
+			m.inputErr = m.openOutputFile(file.path)
+		}
+		return m, nil
+	case "d":
+		if file, ok := m.list.SelectedItem().(outputFile); ok {
+			m.markForDiff(file.path)
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+func (m model) outputsView() string {
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		fmt.Sprintf("Saved outputs in %s (enter to view, d to compare, / to filter, esc to go back):", m.config.OutputDir),
+		m.list.View(),
+	)
+	if hint := m.diffHint(); hint != "" {
+		content = lipgloss.JoinVertical(lipgloss.Left, content, hint)
+	}
+	if m.inputErr != nil {
+		content = lipgloss.JoinVertical(lipgloss.Left, errorStyle.Render(m.inputErr.Error()), content)
+	}
+	return content
+}
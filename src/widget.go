@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Shell widgets that insert the command picked with --print into the
+// command line. %[1]s is replaced with the quoted FabricForge executable.
+var widgets = map[string]string{
+	"zsh": `# FabricForge widget for zsh. Add to ~/.zshrc:
+#   eval "$(%[1]s widget zsh)"
+fabricforge-widget() {
+  local cmd
+  cmd="$(%[1]s --print </dev/tty)"
+  if [[ -n $cmd ]]; then
+    LBUFFER+="$cmd"
+  fi
+  zle reset-prompt
+}
+zle -N fabricforge-widget
+bindkey '^Xf' fabricforge-widget
+`,
+	"bash": `# FabricForge widget for bash. Add to ~/.bashrc:
+#   eval "$(%[1]s widget bash)"
+__fabricforge_widget() {
+  local cmd
+  cmd="$(%[1]s --print </dev/tty)"
+  READLINE_LINE="${READLINE_LINE:0:$READLINE_POINT}$cmd${READLINE_LINE:$READLINE_POINT}"
+  READLINE_POINT=$((READLINE_POINT + ${#cmd}))
+}
+bind -x '"\C-xf": __fabricforge_widget'
+`,
+	"fish": `# FabricForge widget for fish. Add to ~/.config/fish/config.fish:
+#   %[1]s widget fish | source
+function fabricforge_widget
+    set -l cmd (%[1]s --print </dev/tty | string collect)
+    if test -n "$cmd"
+        commandline -i -- $cmd
+    end
+    commandline -f repaint
+end
+bind \cxf fabricforge_widget
+`,
+}
+
+// printWidget writes the widget snippet for the shell named in args, bound
+// to ctrl+x f.
+func printWidget(w io.Writer, args []string) error {
+	if len(args) != 1 || widgets[args[0]] == "" {
+		return fmt.Errorf("usage: %s widget zsh|bash|fish", os.Args[0])
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, widgets[args[0]], shellQuote(exe))
+	return err
+}
@@ -9,22 +9,12 @@ import (
 	"path/filepath"
 	"strings"
 
+	"fabric-ai-cli/utils/metadata"
 	"github.com/joho/godotenv"
 )
 
 // Metadata represents the structure of each JSON file's metadata
-type Metadata struct {
-	DirName             string   `json:"dir_name"`
-	FriendlyName        string   `json:"friendly_name"`
-	ShortDescription    string   `json:"short_description"`
-	Description         string   `json:"description"`
-	Categories          []string `json:"categories"`
-	Tags                []string `json:"tags"`
-	RelatedPatterns     []string `json:"related_patterns"`
-	CharacterCount      int      `json:"character_count"`
-	EstimatedTokenCount int      `json:"estimated_token_count"`
-	UsageExample        string   `json:"usage_example"`
-}
+type Metadata = metadata.Pattern
 
 // CombinedMetadata holds the collection of all metadata
 type CombinedMetadata struct {
@@ -0,0 +1,86 @@
+// Package metadata reads and writes the per-pattern metadata files shared by
+// the tools that rewrite them.
+package metadata
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Pattern is the metadata of one pattern
+type Pattern struct {
+	DirName             string   `json:"dir_name"`
+	FriendlyName        string   `json:"friendly_name"`
+	ShortDescription    string   `json:"short_description"`
+	Description         string   `json:"description"`
+	Categories          []string `json:"categories"`
+	Tags                []string `json:"tags"`
+	RelatedPatterns     []string `json:"related_patterns"`
+	CharacterCount      int      `json:"character_count"`
+	EstimatedTokenCount int      `json:"estimated_token_count"`
+	UsageExample        string   `json:"usage_example"`
+
+	// Optional execution defaults applied when the pattern is run
+	PreferredModel  string   `json:"preferred_model,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"top_p,omitempty"`
+	OutputExtension string   `json:"output_extension,omitempty"`
+
+	// Fields not declared above, passed through unchanged
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// Names of the JSON fields declared on Pattern
+var patternFields = func() map[string]bool {
+	fields := map[string]bool{}
+	t := reflect.TypeOf(Pattern{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}()
+
+// UnmarshalJSON keeps unknown fields in Extra
+func (p *Pattern) UnmarshalJSON(data []byte) error {
+	type plain Pattern
+	if err := json.Unmarshal(data, (*plain)(p)); err != nil {
+		return err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	p.Extra = nil
+	for name, value := range all {
+		if !patternFields[name] {
+			if p.Extra == nil {
+				p.Extra = map[string]json.RawMessage{}
+			}
+			p.Extra[name] = value
+		}
+	}
+	return nil
+}
+
+// MarshalJSON writes the fields in Extra back out
+func (p Pattern) MarshalJSON() ([]byte, error) {
+	type plain Pattern
+	data, err := json.Marshal(plain(p))
+	if err != nil || len(p.Extra) == 0 {
+		return data, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	for name, value := range p.Extra {
+		if !patternFields[name] {
+			all[name] = value
+		}
+	}
+	return json.Marshal(all)
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"fabric-ai-cli/utils/metadata"
 	"github.com/joho/godotenv"
 )
 
@@ -19,18 +20,7 @@ type InputFile struct {
 	Patterns []InputPattern `json:"patterns"`
 }
 
-type ExistingFile struct {
-	DirName             string   `json:"dir_name"`
-	FriendlyName        string   `json:"friendly_name"`
-	ShortDescription    string   `json:"short_description"`
-	Description         string   `json:"description"`
-	Categories          []string `json:"categories"`
-	Tags                []string `json:"tags"`
-	RelatedPatterns     []string `json:"related_patterns"`
-	CharacterCount      int      `json:"character_count"`
-	EstimatedTokenCount int      `json:"estimated_token_count"`
-	UsageExample        string   `json:"usage_example"`
-}
+type ExistingFile = metadata.Pattern
 
 func main() {
 	// Load .env file